@@ -0,0 +1,39 @@
+package hierogolyph
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/evalphobia/hierogolyph/cipher"
+)
+
+var errEmptyAAD = errors.New("aad must not be empty")
+
+// AAD creates additional authenticated data from context parts (e.g. table, column and primary key).
+// Each part is length-prefixed, so that ("ab", "c") and ("a", "bc") are different.
+func AAD(parts ...string) []byte {
+	var byt []byte
+	for _, p := range parts {
+		byt = append(byt, encodeUvarint(uint64(len(p)))...)
+		byt = append(byt, p...)
+	}
+	return byt
+}
+
+// encryptWithAAD encrypts plainText with aad using cipher.AADCipher.
+func encryptWithAAD(c cipher.Cipher, plainText, key, aad []byte) ([]byte, error) {
+	v, ok := c.(cipher.AADCipher)
+	if !ok {
+		return nil, fmt.Errorf("cipher does not support aad: type=[%T]", c)
+	}
+	return v.EncryptWithAAD(plainText, key, aad)
+}
+
+// decryptWithAAD decrypts cipherText with aad using cipher.AADCipher.
+func decryptWithAAD(c cipher.Cipher, cipherText, key, aad []byte) ([]byte, error) {
+	v, ok := c.(cipher.AADCipher)
+	if !ok {
+		return nil, fmt.Errorf("cipher does not support aad: type=[%T]", c)
+	}
+	return v.DecryptWithAAD(cipherText, key, aad)
+}
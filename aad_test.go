@@ -0,0 +1,108 @@
+package hierogolyph
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/cipher/aesgcm"
+	"github.com/evalphobia/hierogolyph/cipher/chacha20poly1305"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAAD(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		parts    []string
+		expected []byte
+	}{
+		{nil, nil},
+		{[]string{""}, []byte{0}},
+		{[]string{"ab", "c"}, []byte("\x02ab\x01c")},
+		{[]string{"a", "bc"}, []byte("\x01a\x02bc")},
+		{[]string{"users", "ssn", "1"}, []byte("\x05users\x03ssn\x011")},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, AAD(tt.parts...), target)
+	}
+}
+
+func TestHierogolyph_EncryptWithAAD(t *testing.T) {
+	a := assert.New(t)
+
+	chachaConf := testConfig
+	chachaConf.Cipher = chacha20poly1305.Cipher{}
+
+	tests := []struct {
+		aad      []byte
+		otherAAD []byte
+	}{
+		{AAD("users", "ssn", "1"), AAD("users", "ssn", "2")},
+		{AAD("users", "ssn", "1"), AAD("users", "phone", "1")},
+		{AAD("users", "ssn", "1"), AAD("users", "ssn1")},
+	}
+
+	for _, conf := range []Config{testConfig, chachaConf} {
+		h, err := CreateHierogolyph("password", conf)
+		a.NoError(err)
+
+		for _, tt := range tests {
+			target := fmt.Sprintf("%s %+v", conf.cipherName(), tt)
+			cipherText, err := h.EncryptWithAAD("plain text", tt.aad)
+			a.NoError(err, target)
+
+			e, err := ParseEnvelope(cipherText)
+			a.NoError(err, target)
+			a.True(e.AAD, target)
+
+			plainText, err := h.DecryptWithAAD(cipherText, tt.aad)
+			a.NoError(err, target)
+			a.Equal("plain text", plainText, target)
+
+			_, err = h.DecryptWithAAD(cipherText, tt.otherAAD)
+			a.Error(err, target)
+			_, err = h.Decrypt(cipherText)
+			a.EqualError(err, "cipherText is bound to aad, use DecryptWithAAD", target)
+		}
+	}
+}
+
+func TestHierogolyph_EncryptWithAADError(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+
+	_, err = h.EncryptWithAAD("plain text", nil)
+	a.EqualError(err, "config is invalid: aad must not be empty")
+	a.True(errors.Is(err, ErrInvalidConfig))
+	_, err = h.DecryptWithAAD("", nil)
+	a.EqualError(err, "config is invalid: aad must not be empty")
+	a.True(errors.Is(err, ErrInvalidConfig))
+
+	// ciphertext without aad
+	cipherText, err := h.Encrypt("plain text")
+	a.NoError(err)
+	_, err = h.DecryptWithAAD(cipherText, AAD("users", "ssn", "1"))
+	a.EqualError(err, "cipherText is not bound to aad")
+
+	// cipher without aad support
+	h.Config.Cipher = testCipher{}
+	_, err = h.EncryptWithAAD("plain text", AAD("users", "ssn", "1"))
+	a.EqualError(err, "cipher does not support aad: type=[hierogolyph.testCipher]")
+}
+
+// testCipher is Cipher without cipher.AADCipher.
+type testCipher struct{}
+
+func (testCipher) Encrypt(plainText string, key []byte) (string, error) {
+	return aesgcm.Cipher{}.Encrypt(plainText, key)
+}
+
+func (testCipher) Decrypt(cipherText string, key []byte) (string, error) {
+	return aesgcm.Cipher{}.Decrypt(cipherText, key)
+}
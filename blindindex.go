@@ -0,0 +1,100 @@
+package hierogolyph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/evalphobia/hierogolyph/pii"
+)
+
+const (
+	// BlindIndexMaxSize is the byte size of untruncated blind index token.
+	BlindIndexMaxSize   = sha256.Size
+	blindIndexMinKeyLen = 16
+)
+
+const blindIndexInfo = "hierogolyph blind index"
+
+// BlindIndex creates deterministic tokens of plain texts for equality search of encrypted values.
+// The key of each field is derived from Key and the field name, so tokens are not comparable between fields.
+// Key must be different from Config.HMACKey, which is checked by Config.Validate.
+type BlindIndex struct {
+	// Key is the root key of the index, at least 16 bytes.
+	Key []byte
+
+	// Size is the byte size of the token, the token is truncated when it's less than BlindIndexMaxSize.
+	// Smaller size causes false positives, which hides exact matches from the index.
+	// BlindIndexMaxSize is used when it's zero.
+	Size int
+
+	// Normalizers normalize plain texts of the field before hashing. (e.g. `"email": pii.Email{}`)
+	Normalizers map[string]pii.Normalizer
+}
+
+// GenerateBlindIndexKey generates a random key for BlindIndex.
+func GenerateBlindIndexKey() ([]byte, error) {
+	return getRandomBytes(BlindIndexMaxSize)
+}
+
+// Token returns hex encoded token of the plainText for the field.
+// (e.g. the value of `email_bidx` column for `email`)
+func (b BlindIndex) Token(field, plainText string) (string, error) {
+	byt, err := b.TokenBytes(field, []byte(plainText))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(byt), nil
+}
+
+// TokenBytes returns token of the plainText for the field.
+func (b BlindIndex) TokenBytes(field string, plainText []byte) ([]byte, error) {
+	size, err := b.validate(field)
+	if err != nil {
+		return nil, err
+	}
+
+	if n, ok := b.Normalizers[field]; ok {
+		normalized, err := pii.Normalize(n, string(plainText))
+		if err != nil {
+			return nil, err
+		}
+		plainText = []byte(normalized)
+	}
+
+	fieldKey := b.fieldKey(field)
+	defer zeroBytes(fieldKey)
+	return hashHMACBytes(plainText, string(fieldKey))[:size], nil
+}
+
+// validate checks the settings and returns token size.
+func (b BlindIndex) validate(field string) (size int, err error) {
+	if err := b.validateKey(); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case field == "":
+		return 0, errors.New("blind index field must not be empty")
+	case b.Size == 0:
+		return BlindIndexMaxSize, nil
+	}
+	return b.Size, nil
+}
+
+// validateKey checks the key and token size.
+func (b BlindIndex) validateKey() error {
+	switch {
+	case len(b.Key) < blindIndexMinKeyLen:
+		return fmt.Errorf("blind index key is too short: size=[%d]", len(b.Key))
+	case b.Size < 0 || b.Size > BlindIndexMaxSize:
+		return fmt.Errorf("blind index size is invalid: size=[%d]", b.Size)
+	}
+	return nil
+}
+
+// fieldKey derives the key of the field from Key.
+func (b BlindIndex) fieldKey(field string) []byte {
+	return hashHMACBytes(AAD(blindIndexInfo, field), string(b.Key))
+}
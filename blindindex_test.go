@@ -0,0 +1,114 @@
+package hierogolyph
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/pii"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlindIndex_Token(t *testing.T) {
+	a := assert.New(t)
+	key := []byte("1234567890123456")
+
+	tests := []struct {
+		field    string
+		text     string
+		size     int
+		expected string
+	}{
+		{"email", "foo@example.com", 0, "05f428f618bd697e906a1802372c0b65fd075847078d7bdc5e823db66685938d"},
+		{"email", "foo@example.com", 32, "05f428f618bd697e906a1802372c0b65fd075847078d7bdc5e823db66685938d"},
+		{"email", "foo@example.com", 8, "05f428f618bd697e"},
+		{"email", "foo@example.com", 1, "05"},
+		{"phone", "foo@example.com", 0, "dd0f2bdde04efa40721d4fe64de324761e6bb5de90e4f5b04be7b6aa07c4008d"},
+		{"email", "bar@example.com", 0, "64ac6e4ff61c3624eaa173f261116eb9dc492a398354870dcc19abd41bca4575"},
+		{"email", "", 0, "1a43f8fca9eb445faaff79243619386aa4935d7f984e1017abbc4e2097de3b20"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		b := BlindIndex{Key: key, Size: tt.size}
+		result, err := b.Token(tt.field, tt.text)
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+	}
+}
+
+func TestBlindIndex_Normalizers(t *testing.T) {
+	a := assert.New(t)
+
+	b := BlindIndex{
+		Key: []byte("1234567890123456"),
+		Normalizers: map[string]pii.Normalizer{
+			"email": pii.Email{StripPlusTag: true},
+		},
+	}
+	expected := "05f428f618bd697e906a1802372c0b65fd075847078d7bdc5e823db66685938d"
+
+	tests := []struct {
+		text string
+	}{
+		{"foo@example.com"},
+		{" Foo@Example.com "},
+		{"foo+news@example.com"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		result, err := b.Token("email", tt.text)
+		a.NoError(err, target)
+		a.Equal(expected, result, target)
+	}
+
+	_, err := b.Token("email", "foo")
+	a.EqualError(err, "email is invalid")
+	// other fields are not normalized.
+	result, err := b.Token("name", " Foo ")
+	a.NoError(err)
+	a.NotEqual(result, mustToken(b, "name", "foo"))
+}
+
+func mustToken(b BlindIndex, field, text string) string {
+	token, err := b.Token(field, text)
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+func TestBlindIndex_TokenError(t *testing.T) {
+	a := assert.New(t)
+	key := []byte("1234567890123456")
+
+	tests := []struct {
+		index    BlindIndex
+		field    string
+		expected string
+	}{
+		{BlindIndex{}, "email", "blind index key is too short: size=[0]"},
+		{BlindIndex{Key: key[:15]}, "email", "blind index key is too short: size=[15]"},
+		{BlindIndex{Key: key, Size: -1}, "email", "blind index size is invalid: size=[-1]"},
+		{BlindIndex{Key: key, Size: 33}, "email", "blind index size is invalid: size=[33]"},
+		{BlindIndex{Key: key}, "", "blind index field must not be empty"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		_, err := tt.index.Token(tt.field, "foo@example.com")
+		a.EqualError(err, tt.expected, target)
+	}
+}
+
+func TestGenerateBlindIndexKey(t *testing.T) {
+	a := assert.New(t)
+
+	key1, err := GenerateBlindIndexKey()
+	a.NoError(err)
+	a.Len(key1, BlindIndexMaxSize)
+	key2, err := GenerateBlindIndexKey()
+	a.NoError(err)
+	a.NotEqual(key1, key2)
+}
@@ -0,0 +1,361 @@
+package hierogolyph
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/evalphobia/hierogolyph/crypto/chacha20poly1305"
+)
+
+const (
+	// KeySlotPassword is a key slot unlocked by password, which is EncryptionKey version 1 with salt.
+	KeySlotPassword = "password"
+	// KeySlotX25519 is a key slot unlocked by X25519 private key (e.g. compliance officer).
+	KeySlotX25519 = "x25519"
+)
+
+const (
+	keyBundlePrefixV1 = "hb1."
+	x25519KeySize     = curve25519.PointSize
+)
+
+var x25519SlotInfo = []byte("hierogolyph x25519 slot")
+
+// field tags of KeyBundle.
+const (
+	bundleTagKeyID byte = iota + 1
+	bundleTagSlot
+)
+
+// field tags of key slot.
+const (
+	slotTagName byte = iota + 1
+	slotTagType
+	slotTagKey
+)
+
+// KeyBundle has multiple wrapped copies of the same Content Encryption Key.
+// Any one of the slots can open Session, and slots can be added or revoked without re-encryption.
+type KeyBundle struct {
+	keyID []byte
+	slots []keySlot
+}
+
+// keySlot is a wrapped key in KeyBundle.
+type keySlot struct {
+	name string
+	typ  string
+	key  []byte
+}
+
+// NewKeyBundle creates empty KeyBundle for the session key.
+// Add slots by AddPasswordSlot or AddX25519Slot.
+func (s *Session) NewKeyBundle() (*KeyBundle, error) {
+	secret, err := s.secret()
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+
+	return &KeyBundle{
+		keyID: createKeyID(secret),
+	}, nil
+}
+
+// AddPasswordSlot adds a slot which is unlocked by the password.
+// Hasher and HSM of the session are used to wrap the key.
+func (s *Session) AddPasswordSlot(b *KeyBundle, name, password string) error {
+	secret, err := s.bundleSecret(b, name)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(secret)
+
+	salt, err := getRandomString(20)
+	if err != nil {
+		return err
+	}
+	key, err := s.conf.newEncryptionKeyV1(password, salt, secret)
+	if err != nil {
+		return err
+	}
+	key.Salt = salt
+
+	b.slots = append(b.slots, keySlot{
+		name: name,
+		typ:  KeySlotPassword,
+		key:  key.marshalBinary(),
+	})
+	return nil
+}
+
+// AddX25519Slot adds a slot which is unlocked by the private key of the publicKey.
+// The key is wrapped by an ephemeral key, so the private key is not required.
+func (s *Session) AddX25519Slot(b *KeyBundle, name string, publicKey []byte) error {
+	if len(publicKey) != x25519KeySize {
+		return fmt.Errorf("x25519 public key is invalid: size=[%d]", len(publicKey))
+	}
+	secret, err := s.bundleSecret(b, name)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(secret)
+
+	ephemeralPublic, ephemeralPrivate, err := GenerateX25519Key()
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(ephemeralPrivate)
+
+	kek, err := createX25519KEK(ephemeralPrivate, publicKey, ephemeralPublic, publicKey)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(kek)
+
+	sealed, err := chacha20poly1305.EncryptWithAAD(secret, kek, b.keyID)
+	if err != nil {
+		return err
+	}
+
+	b.slots = append(b.slots, keySlot{
+		name: name,
+		typ:  KeySlotX25519,
+		key:  append(ephemeralPublic, sealed...),
+	})
+	return nil
+}
+
+// bundleSecret returns the session key, when the key can be added to the bundle.
+func (s *Session) bundleSecret(b *KeyBundle, name string) ([]byte, error) {
+	switch {
+	case name == "":
+		return nil, errors.New("key slot name must not be empty")
+	case b.hasSlot(name):
+		return nil, fmt.Errorf("key slot already exists: name=[%s]", name)
+	}
+
+	secret, err := s.secret()
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(b.keyID, createKeyID(secret)) {
+		zeroBytes(secret)
+		return nil, newError(ErrWrongKey, errors.New("keyBundle has another key"))
+	}
+	return secret, nil
+}
+
+// OpenWithPassword unlocks the password slot of the name and returns Session.
+// Hasher and HSM in conf are used to unwrap the key.
+func (b *KeyBundle) OpenWithPassword(conf Config, name, password string) (*Session, error) {
+	slot, ok := b.findSlot(name)
+	if !ok || slot.typ != KeySlotPassword {
+		return nil, fmt.Errorf("password slot is not found: name=[%s]", name)
+	}
+	key, err := parseEncryptionKeyBinary(slot.key)
+	if err != nil {
+		return nil, newError(ErrWrongKey, err)
+	}
+
+	secret, _, err := conf.unwrapKey(context.Background(), key, password, key.Salt)
+	if err != nil {
+		return nil, err
+	}
+	return b.newSession(conf, secret)
+}
+
+// OpenWithX25519 unlocks the X25519 slot of the privateKey and returns Session.
+func (b *KeyBundle) OpenWithX25519(conf Config, privateKey []byte) (*Session, error) {
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, slot := range b.slots {
+		if slot.typ != KeySlotX25519 || len(slot.key) < x25519KeySize {
+			continue
+		}
+		ephemeralPublic := slot.key[:x25519KeySize]
+		kek, err := createX25519KEK(privateKey, ephemeralPublic, ephemeralPublic, publicKey)
+		if err != nil {
+			continue
+		}
+		secret, err := chacha20poly1305.DecryptWithAAD(slot.key[x25519KeySize:], kek, b.keyID)
+		zeroBytes(kek)
+		if err != nil {
+			continue
+		}
+		return b.newSession(conf, secret)
+	}
+	return nil, newError(ErrWrongKey, errors.New("x25519 slot is not found"))
+}
+
+// newSession verifies the secret and creates Session.
+// EncryptionKey of the session has only the key id, so cipherTexts can be decrypted by any slot.
+func (b *KeyBundle) newSession(conf Config, secret []byte) (*Session, error) {
+	if !hmac.Equal(b.keyID, createKeyID(secret)) {
+		zeroBytes(secret)
+		return nil, newError(ErrWrongKey, errors.New("key id is not matched"))
+	}
+
+	return newSession(conf, newReferenceKey(b.keyID).String(), secret), nil
+}
+
+// Revoke removes the slot of the name.
+// The last slot cannot be revoked, because the key cannot be opened after that.
+func (b *KeyBundle) Revoke(name string) error {
+	for i, slot := range b.slots {
+		if slot.name != name {
+			continue
+		}
+		if len(b.slots) == 1 {
+			return errors.New("the last key slot cannot be revoked")
+		}
+		b.slots = append(b.slots[:i:i], b.slots[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("key slot is not found: name=[%s]", name)
+}
+
+// SlotNames returns names of the slots.
+func (b *KeyBundle) SlotNames() []string {
+	names := make([]string, len(b.slots))
+	for i, slot := range b.slots {
+		names[i] = slot.name
+	}
+	return names
+}
+
+// hasSlot reports whether the bundle has the slot of the name.
+func (b *KeyBundle) hasSlot(name string) bool {
+	_, ok := b.findSlot(name)
+	return ok
+}
+
+// findSlot returns the slot of the name.
+func (b *KeyBundle) findSlot(name string) (keySlot, bool) {
+	for _, slot := range b.slots {
+		if slot.name == name {
+			return slot, true
+		}
+	}
+	return keySlot{}, false
+}
+
+// String returns text form of KeyBundle, `hb1.base64(fields)`.
+func (b *KeyBundle) String() string {
+	byt := appendField(nil, bundleTagKeyID, b.keyID)
+	for _, slot := range b.slots {
+		v := appendField(nil, slotTagName, []byte(slot.name))
+		v = appendField(v, slotTagType, []byte(slot.typ))
+		v = appendField(v, slotTagKey, slot.key)
+		byt = appendField(byt, bundleTagSlot, v)
+	}
+	return keyBundlePrefixV1 + encodeBase64(byt)
+}
+
+// ParseKeyBundle parses text form of KeyBundle.
+func ParseKeyBundle(text string) (*KeyBundle, error) {
+	if !strings.HasPrefix(text, keyBundlePrefixV1) {
+		return nil, errors.New("keyBundle has unknown prefix")
+	}
+	byt, err := decodeBase64(strings.TrimPrefix(text, keyBundlePrefixV1))
+	if err != nil {
+		return nil, err
+	}
+
+	b := &KeyBundle{}
+	rest := []byte(byt)
+	for len(rest) > 0 {
+		tag, value, next, err := readField(rest)
+		if err != nil {
+			return nil, fmt.Errorf("keyBundle field is broken: tag=[%d]", rest[0])
+		}
+		rest = next
+
+		switch tag {
+		case bundleTagKeyID:
+			b.keyID = value
+		case bundleTagSlot:
+			slot, err := parseKeySlot(value)
+			if err != nil {
+				return nil, err
+			}
+			b.slots = append(b.slots, slot)
+		default:
+			return nil, fmt.Errorf("keyBundle has unknown field: tag=[%d]", tag)
+		}
+	}
+
+	if len(b.keyID) != keyIDSize {
+		return nil, errors.New("keyBundle does not have key id")
+	}
+	return b, nil
+}
+
+// parseKeySlot parses binary form of key slot.
+func parseKeySlot(byt []byte) (keySlot, error) {
+	slot := keySlot{}
+	for len(byt) > 0 {
+		tag, value, rest, err := readField(byt)
+		if err != nil {
+			return keySlot{}, fmt.Errorf("key slot field is broken: tag=[%d]", byt[0])
+		}
+		byt = rest
+
+		switch tag {
+		case slotTagName:
+			slot.name = string(value)
+		case slotTagType:
+			slot.typ = string(value)
+		case slotTagKey:
+			slot.key = value
+		default:
+			return keySlot{}, fmt.Errorf("key slot has unknown field: tag=[%d]", tag)
+		}
+	}
+
+	if slot.name == "" || len(slot.key) == 0 {
+		return keySlot{}, errors.New("key slot does not have required fields")
+	}
+	return slot, nil
+}
+
+// GenerateX25519Key generates a key pair for X25519 slot.
+func GenerateX25519Key() (publicKey, privateKey []byte, err error) {
+	privateKey, err = getRandomBytes(x25519KeySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err = curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return publicKey, privateKey, nil
+}
+
+// createX25519KEK creates Key Encryption Key from X25519 shared secret.
+// Both of the public keys are bound to the key.
+func createX25519KEK(privateKey, peerPublicKey, ephemeralPublic, recipientPublic []byte) ([]byte, error) {
+	shared, err := curve25519.X25519(privateKey, peerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(shared)
+
+	salt := append(append([]byte{}, ephemeralPublic...), recipientPublic...)
+	kek := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, x25519SlotInfo), kek); err != nil {
+		return nil, err
+	}
+	return kek, nil
+}
@@ -0,0 +1,136 @@
+package hierogolyph
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyBundle(t *testing.T) {
+	a := assert.New(t)
+
+	legacy := testHierogolyph1
+	legacy.Config = testConfig
+	created, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	other, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+
+	for _, h := range []Hierogolyph{legacy, created} {
+		target := h.EncryptionKey
+		cipherText, err := h.Encrypt("plain text")
+		a.NoError(err, target)
+
+		s, err := h.Open()
+		a.NoError(err, target)
+		b, err := s.NewKeyBundle()
+		a.NoError(err, target)
+		a.NoError(s.AddPasswordSlot(b, "user", "bundle password"), target)
+		publicKey, privateKey, err := GenerateX25519Key()
+		a.NoError(err, target)
+		a.NoError(s.AddX25519Slot(b, "compliance", publicKey), target)
+		a.NoError(s.Close(), target)
+		a.Equal([]string{"user", "compliance"}, b.SlotNames(), target)
+
+		b, err = ParseKeyBundle(b.String())
+		a.NoError(err, target)
+		a.Equal([]string{"user", "compliance"}, b.SlotNames(), target)
+
+		// any one of the slots can decrypt.
+		byPassword, err := b.OpenWithPassword(testConfig, "user", "bundle password")
+		a.NoError(err, target)
+		plainText, err := byPassword.Decrypt(cipherText)
+		a.NoError(err, target)
+		a.Equal("plain text", plainText, target)
+
+		byX25519, err := b.OpenWithX25519(testConfig, privateKey)
+		a.NoError(err, target)
+		plainText, err = byX25519.Decrypt(cipherText)
+		a.NoError(err, target)
+		a.Equal("plain text", plainText, target)
+
+		// cipherText of the bundle can be decrypted by another slot.
+		bundleCipherText, err := byX25519.Encrypt("bundle text")
+		a.NoError(err, target)
+		plainText, err = byPassword.Decrypt(bundleCipherText)
+		a.NoError(err, target)
+		a.Equal("bundle text", plainText, target)
+
+		// cipherText of the bundle can be decrypted by the owner.
+		plainText, err = h.Decrypt(bundleCipherText)
+		a.NoError(err, target)
+		a.Equal("bundle text", plainText, target)
+		_, err = other.Decrypt(bundleCipherText)
+		a.True(errors.Is(err, ErrWrongKey), target)
+
+		// wrong password and private key
+		_, err = b.OpenWithPassword(testConfig, "user", "bad-p4ssw0rd")
+		a.True(errors.Is(err, ErrWrongKey), target)
+		_, otherKey, err := GenerateX25519Key()
+		a.NoError(err, target)
+		_, err = b.OpenWithX25519(testConfig, otherKey)
+		a.True(errors.Is(err, ErrWrongKey), target)
+
+		// revoke
+		a.NoError(b.Revoke("compliance"), target)
+		a.Equal([]string{"user"}, b.SlotNames(), target)
+		_, err = b.OpenWithX25519(testConfig, privateKey)
+		a.True(errors.Is(err, ErrWrongKey), target)
+		a.EqualError(b.Revoke("user"), "the last key slot cannot be revoked", target)
+		a.EqualError(b.Revoke("compliance"), "key slot is not found: name=[compliance]", target)
+
+		// slot name
+		a.EqualError(byPassword.AddPasswordSlot(b, "user", "password"), "key slot already exists: name=[user]", target)
+		a.EqualError(byPassword.AddPasswordSlot(b, "", "password"), "key slot name must not be empty", target)
+		_, err = b.OpenWithPassword(testConfig, "compliance", "password")
+		a.EqualError(err, "password slot is not found: name=[compliance]", target)
+	}
+
+	// cipherText of the bundle can be decrypted by Hierogolyph of the same key.
+	s, err := created.Open()
+	a.NoError(err)
+	b, err := s.NewKeyBundle()
+	a.NoError(err)
+	a.NoError(s.AddPasswordSlot(b, "user", "bundle password"))
+	s, err = b.OpenWithPassword(testConfig, "user", "bundle password")
+	a.NoError(err)
+	cipherText, err := s.Encrypt("plain text")
+	a.NoError(err)
+	plainText, err := created.Decrypt(cipherText)
+	a.NoError(err)
+	a.Equal("plain text", plainText)
+
+	// slot of another key
+	otherSession, err := other.Open()
+	a.NoError(err)
+	err = otherSession.AddPasswordSlot(b, "other", "password")
+	a.True(errors.Is(err, ErrWrongKey))
+	_, err = other.Decrypt(cipherText)
+	a.True(errors.Is(err, ErrWrongKey))
+}
+
+func TestParseKeyBundle(t *testing.T) {
+	a := assert.New(t)
+
+	keyID := appendField(nil, bundleTagKeyID, []byte("12345678"))
+	tests := []struct {
+		expected string
+		text     string
+	}{
+		{"keyBundle has unknown prefix", "hk1."},
+		{"illegal base64 data at input byte 0", "hb1.!"},
+		{"keyBundle does not have key id", "hb1."},
+		{"keyBundle field is broken: tag=[1]", "hb1." + encodeBase64([]byte{1, 5})},
+		{"keyBundle has unknown field: tag=[99]", "hb1." + encodeBase64([]byte{99, 0})},
+		{"key slot does not have required fields", "hb1." + encodeBase64(appendField(keyID, bundleTagSlot, nil))},
+		{"key slot has unknown field: tag=[99]", "hb1." + encodeBase64(appendField(keyID, bundleTagSlot, []byte{99, 0}))},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		_, err := ParseKeyBundle(tt.text)
+		a.EqualError(err, tt.expected, target)
+	}
+}
@@ -0,0 +1,27 @@
+package cipher
+
+// ToByteCipher returns ByteCipher from Cipher.
+// When c does not implement ByteCipher, byte slices are converted into string for c.
+func ToByteCipher(c Cipher) ByteCipher {
+	if v, ok := c.(ByteCipher); ok {
+		return v
+	}
+	return byteCipher{c}
+}
+
+// byteCipher is adapter of Cipher for ByteCipher.
+type byteCipher struct {
+	Cipher
+}
+
+// EncryptBytes encrypts plainText.
+func (c byteCipher) EncryptBytes(plainText, key []byte) ([]byte, error) {
+	cipherText, err := c.Encrypt(string(plainText), key)
+	return []byte(cipherText), err
+}
+
+// DecryptBytes decrypts cipherText.
+func (c byteCipher) DecryptBytes(cipherText, key []byte) ([]byte, error) {
+	plainText, err := c.Decrypt(string(cipherText), key)
+	return []byte(plainText), err
+}
@@ -0,0 +1,59 @@
+package cipher_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/cipher"
+	"github.com/evalphobia/hierogolyph/cipher/aesgcm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stringCipher implements only cipher.Cipher.
+type stringCipher struct {
+	c aesgcm.Cipher
+}
+
+func (s stringCipher) Encrypt(plainText string, key []byte) (string, error) {
+	return s.c.Encrypt(plainText, key)
+}
+
+func (s stringCipher) Decrypt(cipherText string, key []byte) (string, error) {
+	return s.c.Decrypt(cipherText, key)
+}
+
+func TestToByteCipher(t *testing.T) {
+	a := assert.New(t)
+	key := []byte("12345678901234567890123456789012")
+
+	tests := []struct {
+		text []byte
+	}{
+		{[]byte("a")},
+		{[]byte("あいうえお")},
+		{[]byte{0x00, 0xff, 0x80}},
+		{[]byte{}},
+	}
+
+	a.Equal(aesgcm.Cipher{}, cipher.ToByteCipher(aesgcm.Cipher{}))
+
+	for _, c := range []cipher.Cipher{aesgcm.Cipher{}, stringCipher{}} {
+		bc := cipher.ToByteCipher(c)
+		for _, tt := range tests {
+			target := fmt.Sprintf("%T %+v", c, tt)
+
+			cipherText, err := bc.EncryptBytes(tt.text, key)
+			a.NoError(err, target)
+
+			plainText, err := bc.DecryptBytes(cipherText, key)
+			a.NoError(err, target)
+			a.Equal(string(tt.text), string(plainText), target)
+
+			// compatible with string interface.
+			plainString, err := c.Decrypt(string(cipherText), key)
+			a.NoError(err, target)
+			a.Equal(string(tt.text), plainString, target)
+		}
+	}
+}
@@ -1,11 +1,20 @@
 package aesgcm
 
 import (
+	"crypto/cipher"
+
 	"github.com/evalphobia/hierogolyph/crypto/aesgcm"
 )
 
+const algorithmName = "aes-256-gcm"
+
 type Cipher struct{}
 
+// Algorithm returns algorithm name.
+func (Cipher) Algorithm() string {
+	return algorithmName
+}
+
 // Encrypt encrypts plainText.
 func (Cipher) Encrypt(plainText string, key []byte) (cipherText string, err error) {
 	byt, err := aesgcm.Encrypt(plainText, key)
@@ -17,3 +26,28 @@ func (Cipher) Decrypt(cipherText string, key []byte) (plainText string, err erro
 	byt, err := aesgcm.Decrypt([]byte(cipherText), key)
 	return string(byt), err
 }
+
+// EncryptBytes encrypts plainText.
+func (Cipher) EncryptBytes(plainText, key []byte) (cipherText []byte, err error) {
+	return aesgcm.EncryptBytes(plainText, key)
+}
+
+// DecryptBytes decrypts cipherText.
+func (Cipher) DecryptBytes(cipherText, key []byte) (plainText []byte, err error) {
+	return aesgcm.DecryptBytes(cipherText, key)
+}
+
+// EncryptWithAAD encrypts plainText with additional data.
+func (Cipher) EncryptWithAAD(plainText, key, aad []byte) (cipherText []byte, err error) {
+	return aesgcm.EncryptWithAAD(plainText, key, aad)
+}
+
+// DecryptWithAAD decrypts cipherText with additional data.
+func (Cipher) DecryptWithAAD(cipherText, key, aad []byte) (plainText []byte, err error) {
+	return aesgcm.DecryptWithAAD(cipherText, key, aad)
+}
+
+// NewAEAD returns AEAD used for streaming encryption.
+func (Cipher) NewAEAD(key []byte) (cipher.AEAD, error) {
+	return aesgcm.NewAEAD(key)
+}
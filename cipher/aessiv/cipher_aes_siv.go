@@ -0,0 +1,86 @@
+package aessiv
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/evalphobia/hierogolyph/crypto/aessiv"
+)
+
+const (
+	algorithmName = "aes-256-siv"
+	inputKeySize  = 32
+)
+
+var keyInfo = []byte("hierogolyph aes-siv")
+
+// Cipher is deterministic AES-SIV cipher (RFC 5297).
+// The same plainText and key produce the same cipherText, which can be used for equality join.
+// 64byte key of AES-256-SIV is derived from the first 32byte of the given key.
+type Cipher struct{}
+
+// Algorithm returns algorithm name.
+func (Cipher) Algorithm() string {
+	return algorithmName
+}
+
+// Deterministic returns true.
+func (Cipher) Deterministic() bool {
+	return true
+}
+
+// Encrypt encrypts plainText.
+func (c Cipher) Encrypt(plainText string, key []byte) (cipherText string, err error) {
+	byt, err := c.EncryptBytes([]byte(plainText), key)
+	return string(byt), err
+}
+
+// Decrypt decrypts cipherText.
+func (c Cipher) Decrypt(cipherText string, key []byte) (plainText string, err error) {
+	byt, err := c.DecryptBytes([]byte(cipherText), key)
+	return string(byt), err
+}
+
+// EncryptBytes encrypts plainText.
+func (c Cipher) EncryptBytes(plainText, key []byte) (cipherText []byte, err error) {
+	return c.EncryptWithAAD(plainText, key, nil)
+}
+
+// DecryptBytes decrypts cipherText.
+func (c Cipher) DecryptBytes(cipherText, key []byte) (plainText []byte, err error) {
+	return c.DecryptWithAAD(cipherText, key, nil)
+}
+
+// EncryptWithAAD encrypts plainText with additional data.
+func (Cipher) EncryptWithAAD(plainText, key, aad []byte) (cipherText []byte, err error) {
+	sivKey, err := deriveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return aessiv.EncryptWithAAD(plainText, sivKey, aad)
+}
+
+// DecryptWithAAD decrypts cipherText with additional data.
+func (Cipher) DecryptWithAAD(cipherText, key, aad []byte) (plainText []byte, err error) {
+	sivKey, err := deriveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return aessiv.DecryptWithAAD(cipherText, sivKey, aad)
+}
+
+// deriveKey derives AES-256-SIV key from the first 32byte of the key.
+func deriveKey(key []byte) ([]byte, error) {
+	if len(key) < inputKeySize {
+		return nil, fmt.Errorf("aessiv: invalid key size %d", len(key))
+	}
+
+	sivKey := make([]byte, aessiv.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key[:inputKeySize], nil, keyInfo), sivKey); err != nil {
+		return nil, err
+	}
+	return sivKey, nil
+}
@@ -0,0 +1,67 @@
+package aessiv
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCipher(t *testing.T) {
+	a := assert.New(t)
+	validKey := "12345678901234567890123456789012" // 32byte
+	invalidKey := "X2345678901234567890123456789012"
+	shortKey := "too short"
+	longKey := validKey + "XYZ" // 35byte
+
+	tests := []struct {
+		text string
+	}{
+		{"a"},
+		{"aaa"},
+		{"あいうえお"},
+		{""},
+	}
+
+	c := Cipher{}
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		_, err := c.Encrypt(tt.text, []byte(shortKey))
+		a.EqualError(err, "aessiv: invalid key size 9", target)
+
+		// encryption is deterministic.
+		cipher1, err := c.Encrypt(tt.text, []byte(validKey))
+		a.NoError(err, target)
+		cipher1Again, err := c.Encrypt(tt.text, []byte(validKey))
+		a.NoError(err, target)
+		a.Equal(cipher1, cipher1Again, target)
+
+		cipher2, err := c.Encrypt(tt.text, []byte(invalidKey))
+		a.NoError(err, target)
+		a.NotEqual(cipher1, cipher2, target, "using invalid key")
+
+		// decryption
+		plainText1, err := c.Decrypt(cipher1, []byte(validKey))
+		a.NoError(err, target)
+		a.Equal(tt.text, plainText1, target)
+
+		_, err = c.Decrypt(cipher1, []byte(invalidKey))
+		a.EqualError(err, "cipher: message authentication failed", target)
+
+		// long key should be used as first 32byte key.
+		plainTextLongKey, err := c.Decrypt(cipher1, []byte(longKey))
+		a.NoError(err, target)
+		a.Equal(tt.text, plainTextLongKey, target)
+
+		// aad
+		cipher3, err := c.EncryptWithAAD([]byte(tt.text), []byte(validKey), []byte("users.ssn.1"))
+		a.NoError(err, target)
+		a.NotEqual(cipher1, cipher3, target)
+		plainText3, err := c.DecryptWithAAD(cipher3, []byte(validKey), []byte("users.ssn.1"))
+		a.NoError(err, target)
+		a.Equal(tt.text, string(plainText3), target)
+		_, err = c.DecryptWithAAD(cipher3, []byte(validKey), []byte("users.ssn.2"))
+		a.EqualError(err, "cipher: message authentication failed", target)
+	}
+}
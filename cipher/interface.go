@@ -1,7 +1,40 @@
 package cipher
 
+import "crypto/cipher"
+
 // Cipher is interface for encryption algorithm.
 type Cipher interface {
 	Encrypt(plainText string, key []byte) (cipherText string, err error)
 	Decrypt(cipherText string, key []byte) (plainText string, err error)
 }
+
+// Algorithm is interface for Cipher which has a stable algorithm name.
+// The name is recorded in the ciphertext envelope.
+type Algorithm interface {
+	Algorithm() string
+}
+
+// ByteCipher is interface for encryption algorithm using byte slices.
+type ByteCipher interface {
+	EncryptBytes(plainText, key []byte) (cipherText []byte, err error)
+	DecryptBytes(cipherText, key []byte) (plainText []byte, err error)
+}
+
+// AEADCipher is interface for Cipher which provides AEAD primitive.
+// It's required for streaming encryption.
+type AEADCipher interface {
+	NewAEAD(key []byte) (cipher.AEAD, error)
+}
+
+// AADCipher is interface for Cipher which supports additional authenticated data.
+// Decryption fails when the additional data is different from the one used for encryption.
+type AADCipher interface {
+	EncryptWithAAD(plainText, key, aad []byte) (cipherText []byte, err error)
+	DecryptWithAAD(cipherText, key, aad []byte) (plainText []byte, err error)
+}
+
+// Deterministic is interface for Cipher which produces the same cipherText from the same plainText and key.
+// It leaks equality of plainTexts, so it must be enabled by Config.Deterministic explicitly.
+type Deterministic interface {
+	Deterministic() bool
+}
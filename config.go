@@ -1,6 +1,10 @@
 package hierogolyph
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+
 	"github.com/evalphobia/hierogolyph/cipher"
 	"github.com/evalphobia/hierogolyph/hasher"
 	"github.com/evalphobia/hierogolyph/hsm"
@@ -21,4 +25,200 @@ type Config struct {
 
 	// HMACKey is the key used for signing message with HMAC.
 	HMACKey string
+
+	// Deterministic enables deterministic Cipher (e.g. AES-SIV), which produces the same cipherText from the same plainText.
+	// It's used for equality join of low-risk values, because it leaks equality of plainTexts.
+	Deterministic bool
+
+	// Padding pads plainText before encryption to hide the length, and the policy is recorded in the envelope.
+	// (e.g. `&Padding{Mode: PaddingPowerOfTwo, BlockSize: 16}`)
+	// It's not padded when it's nil.
+	Padding *Padding
+
+	// Encoding is the text form of cipherText, EncodingBase64 is used by default.
+	// Decrypt detects the encoding automatically.
+	Encoding Encoding
+
+	// BlindIndex creates blind index of struct fields tagged by `pii:"bidx=..."`, see EncryptStruct.
+	BlindIndex *BlindIndex
+
+	// Registry resolves Cipher, Hasher and HSM recorded in the ciphertext on decryption,
+	// when they are different from the above.
+	// DefaultRegistry is used when it's nil.
+	Registry *Registry
+}
+
+// minDigestSize is the byte size of Hasher digest required for Z1 and Z2.
+const minDigestSize = 32
+
+// Validate checks Cipher, HSM and Hasher are set and the sizes are valid.
+// The size of HSM key (hsm.Validator) is also checked.
+// It returns ErrInvalidConfig.
+func (c Config) Validate() error {
+	if err := c.validate(); err != nil {
+		return newError(ErrInvalidConfig, err)
+	}
+	return nil
+}
+
+// validate checks the config.
+func (c Config) validate() error {
+	switch {
+	case c.Cipher == nil:
+		return errors.New("cipher is nil")
+	case c.HSM == nil:
+		return errors.New("hsm is nil")
+	case c.Hasher == nil:
+		return errors.New("hasher is nil")
+	case c.Encoding < EncodingBase64 || c.Encoding > EncodingBinary:
+		return fmt.Errorf("encoding is unknown: encoding=[%d]", c.Encoding)
+	}
+
+	if err := c.validateDeterministic(); err != nil {
+		return err
+	}
+	if v, ok := c.HSM.(hsm.Validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := c.Hasher.(hasher.Validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	if v, ok := c.Hasher.(hasher.Sizer); ok && v.Size() < minDigestSize {
+		return fmt.Errorf("hasher digest is too short: size=[%d]", v.Size())
+	}
+	if c.Padding != nil {
+		if err := c.Padding.validate(); err != nil {
+			return err
+		}
+	}
+	if c.BlindIndex != nil {
+		if err := c.BlindIndex.validateKey(); err != nil {
+			return err
+		}
+		if bytes.Equal(c.BlindIndex.Key, []byte(c.HMACKey)) {
+			return errors.New("blind index key must be different from hmac key")
+		}
+	}
+	return nil
+}
+
+// cipherName returns algorithm name of Cipher.
+func (c Config) cipherName() string {
+	if v, ok := c.Cipher.(cipher.Algorithm); ok {
+		return v.Algorithm()
+	}
+	return ""
+}
+
+// hasherName returns algorithm name and cost parameters of Hasher.
+func (c Config) hasherName() (name, params string) {
+	if v, ok := c.Hasher.(hasher.Algorithm); ok {
+		return v.Algorithm(), v.Params()
+	}
+	return "", ""
+}
+
+// hsmName returns provider name of HSM.
+func (c Config) hsmName() string {
+	if v, ok := c.HSM.(hsm.Provider); ok {
+		return v.Provider()
+	}
+	return ""
+}
+
+// validateDeterministic checks Config.Deterministic is matched with Cipher.
+func (c Config) validateDeterministic() error {
+	switch {
+	case c.isDeterministic() && !c.Deterministic:
+		return fmt.Errorf("deterministic cipher requires Config.Deterministic: type=[%T]", c.Cipher)
+	case !c.isDeterministic() && c.Deterministic:
+		return fmt.Errorf("Config.Deterministic requires deterministic cipher: type=[%T]", c.Cipher)
+	}
+	return nil
+}
+
+// isDeterministic reports whether Cipher is deterministic.
+func (c Config) isDeterministic() bool {
+	v, ok := c.Cipher.(cipher.Deterministic)
+	return ok && v.Deterministic()
+}
+
+// newEnvelope creates Envelope with algorithms in the config.
+func (c Config) newEnvelope(encryptionKey string, cipherText []byte) Envelope {
+	hasherName, hasherParams := c.hasherName()
+	return Envelope{
+		Version:       EnvelopeVersion1,
+		Cipher:        c.cipherName(),
+		Hasher:        hasherName,
+		HasherParams:  hasherParams,
+		HSM:           c.hsmName(),
+		Payload:       PayloadBinary,
+		Deterministic: c.isDeterministic(),
+		EncryptionKey: encryptionKey,
+		CipherText:    cipherText,
+	}
+}
+
+// pad pads payload by Padding, and returns the policy used.
+func (c Config) pad(payload []byte) ([]byte, Padding, error) {
+	if c.Padding == nil {
+		return payload, Padding{}, nil
+	}
+
+	padded, err := c.Padding.pad(payload)
+	if err != nil {
+		return nil, Padding{}, err
+	}
+	return padded, *c.Padding, nil
+}
+
+// resolve returns Config which has algorithms recorded in the envelope.
+// Empty value in the envelope means the same algorithm as the config.
+func (c Config) resolve(e Envelope) (Config, error) {
+	c, err := c.resolveCipher(e)
+	if err != nil {
+		return c, err
+	}
+	r := c.getRegistry()
+
+	hasherName, hasherParams := c.hasherName()
+	if e.Hasher != "" && (e.Hasher != hasherName || e.HasherParams != hasherParams) {
+		c.Hasher, err = r.Hasher(e.Hasher, e.HasherParams)
+		if err != nil {
+			return c, newError(ErrHasher, err)
+		}
+	}
+
+	// HSM recorded in EncryptionKey is resolved on unwrapping, see resolveKeyHSM.
+	key, _ := parseEncryptionKey(e.EncryptionKey)
+	if e.HSM != "" && e.HSM != c.hsmName() && key.HSM == "" {
+		c.HSM, err = r.HSM(e.HSM)
+		if err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// resolveCipher returns Config which has Cipher recorded in the envelope.
+func (c Config) resolveCipher(e Envelope) (Config, error) {
+	if e.Cipher == "" || e.Cipher == c.cipherName() {
+		return c, nil
+	}
+
+	var err error
+	c.Cipher, err = c.getRegistry().Cipher(e.Cipher)
+	return c, err
+}
+
+func (c Config) getRegistry() *Registry {
+	if c.Registry == nil {
+		return DefaultRegistry
+	}
+	return c.Registry
 }
@@ -0,0 +1,82 @@
+package hierogolyph
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/cipher/aessiv"
+	"github.com/evalphobia/hierogolyph/hasher/argon2"
+	"github.com/evalphobia/hierogolyph/hasher/scrypt"
+	hsmgcm "github.com/evalphobia/hierogolyph/hsm/aesgcm"
+	hsmchacha "github.com/evalphobia/hierogolyph/hsm/chacha20poly1305"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	a := assert.New(t)
+	a.NoError(testConfig.Validate())
+
+	with := func(fn func(c *Config)) Config {
+		c := testConfig
+		fn(&c)
+		return c
+	}
+
+	tests := []struct {
+		conf     Config
+		expected string
+	}{
+		{Config{}, "cipher is nil"},
+		{with(func(c *Config) { c.HSM = nil }), "hsm is nil"},
+		{with(func(c *Config) { c.Hasher = nil }), "hasher is nil"},
+		{with(func(c *Config) { c.Hasher = argon2.Argon2{KeyLength: 16} }), "hasher digest is too short: size=[16]"},
+		{with(func(c *Config) { c.Hasher = scrypt.SCrypt{Cost: 3} }), "scrypt: cost must be > 1 and a power of 2: n=[3]"},
+		{with(func(c *Config) { c.Cipher = aessiv.Cipher{} }), "deterministic cipher requires Config.Deterministic: type=[aessiv.Cipher]"},
+		{with(func(c *Config) { c.Deterministic = true }), "Config.Deterministic requires deterministic cipher: type=[aesgcm.Cipher]"},
+		{with(func(c *Config) { c.Encoding = 99 }), "encoding is unknown: encoding=[99]"},
+		{with(func(c *Config) { c.Padding = &Padding{Mode: PaddingBlock} }), "padding size is invalid: block=[0] max=[0]"},
+		{with(func(c *Config) { c.BlindIndex = &BlindIndex{Key: []byte("short")} }), "blind index key is too short: size=[5]"},
+		{with(func(c *Config) { c.BlindIndex = &BlindIndex{Key: []byte(c.HMACKey)} }), "blind index key must be different from hmac key"},
+		{with(func(c *Config) { c.HSM = hsmgcm.NewMockHSM([]byte("short")) }), "hsm key size is invalid: size=[5]"},
+		{with(func(c *Config) { c.HSM = hsmchacha.NewMockHSM([]byte(testGCMKey256[:16])) }), "hsm key size is invalid: size=[16]"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt.expected)
+		err := tt.conf.Validate()
+		a.EqualError(err, "config is invalid: "+tt.expected, target)
+		a.True(errors.Is(err, ErrInvalidConfig), target)
+
+		_, err = CreateHierogolyph("password", tt.conf)
+		a.True(errors.Is(err, ErrInvalidConfig), target)
+	}
+}
+
+func TestConfig_NilInterface(t *testing.T) {
+	a := assert.New(t)
+
+	h := testHierogolyph1
+	h.Config = testConfig
+
+	// runtime errors without panic
+	h.Config.Cipher = nil
+	_, err := h.Encrypt("plain text")
+	a.True(errors.Is(err, ErrInvalidConfig), err)
+	// legacy format doesn't record Cipher to resolve.
+	_, err = h.Decrypt(testLegacyCipherText1)
+	a.True(errors.Is(err, ErrInvalidConfig), err)
+
+	h.Config = testConfig
+	h.Config.HSM = nil
+	_, err = h.Encrypt("plain text")
+	a.True(errors.Is(err, ErrHSM), err)
+	_, err = CreateHierogolyph("password", Config{Cipher: testConfig.Cipher, Hasher: testConfig.Hasher})
+	a.True(errors.Is(err, ErrInvalidConfig), err)
+
+	h.Config = testConfig
+	h.Config.Hasher = scrypt.SCrypt{Cost: 3}
+	_, err = h.Unlock()
+	a.EqualError(err, "hasher error: scrypt: cost must be > 1 and a power of 2: n=[3]")
+}
@@ -0,0 +1,64 @@
+package hierogolyph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/hasher/argon2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countHasher counts HashBytes and Derive calls.
+type countHasher struct {
+	argon2.Argon2
+	count *int
+}
+
+func (h countHasher) HashBytes(password, salt []byte) []byte {
+	*h.count++
+	return h.Argon2.HashBytes(password, salt)
+}
+
+func (h countHasher) Derive(password, salt []byte, outLen int) ([]byte, error) {
+	*h.count++
+	return h.Argon2.Derive(password, salt, outLen)
+}
+
+func TestHierogolyph_Context(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+
+	ctx := context.Background()
+	cipherText, err := h.EncryptContext(ctx, "plain text")
+	a.NoError(err)
+	plainText, err := h.DecryptContext(ctx, cipherText)
+	a.NoError(err)
+	a.Equal("plain text", plainText)
+
+	cek1, err := h.Unlock()
+	a.NoError(err)
+	cek2, err := h.UnlockContext(ctx)
+	a.NoError(err)
+	a.Equal(cek1, cek2)
+
+	// canceled context skips Hasher.
+	count := 0
+	h.Config.Hasher = countHasher{count: &count}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = h.UnlockContext(canceled)
+	a.Equal(context.Canceled, err)
+	_, err = h.EncryptContext(canceled, "plain text")
+	a.Equal(context.Canceled, err)
+	_, err = h.DecryptContext(canceled, cipherText)
+	a.Equal(context.Canceled, err)
+	a.Equal(0, count)
+
+	_, err = h.UnlockContext(ctx)
+	a.NoError(err)
+	a.Equal(1, count)
+}
@@ -9,6 +9,11 @@ import (
 
 // Encrypt encrypts plainText using AES GCM mode.
 func Encrypt(plainText string, key []byte) ([]byte, error) {
+	return EncryptBytes([]byte(plainText), key)
+}
+
+// NewAEAD returns AES GCM mode AEAD.
+func NewAEAD(key []byte) (cipher.AEAD, error) {
 	// use first 32byte if the key length is longer than 32byte.
 	if len(key) > 32 {
 		key = key[0:32]
@@ -18,50 +23,57 @@ func Encrypt(plainText string, key []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptBytes encrypts plainText using AES GCM mode.
+func EncryptBytes(plainText, key []byte) ([]byte, error) {
+	return EncryptWithAAD(plainText, key, nil)
+}
 
-	gcm, err := cipher.NewGCM(block)
+// EncryptWithAAD encrypts plainText with additional data using AES GCM mode.
+// The same additional data is required for decryption.
+func EncryptWithAAD(plainText, key, aad []byte) ([]byte, error) {
+	gcm, err := NewAEAD(key)
 	if err != nil {
 		return nil, err
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
+	// allocate the result buffer at once, nonce is prepended to the sealed text.
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plainText)+gcm.Overhead())
 	_, err = rand.Read(nonce)
 	if err != nil {
 		return nil, err
 	}
 
-	cipherText := gcm.Seal(nil, nonce, []byte(plainText), nil)
-	cipherText = append(nonce, cipherText...)
+	cipherText := gcm.Seal(nonce, nonce, plainText, aad)
 
 	return cipherText, nil
 }
 
 // Decrypt decrypts cipherText using AES GCM mode.
 func Decrypt(cipherText, key []byte) (string, error) {
-	// use first 32byte if the key length is longer than 32byte.
-	if len(key) > 32 {
-		key = key[0:32]
-	}
+	plainByte, err := DecryptBytes(cipherText, key)
+	return string(plainByte), err
+}
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
-	}
-	gcm, err := cipher.NewGCM(block)
+// DecryptBytes decrypts cipherText using AES GCM mode.
+func DecryptBytes(cipherText, key []byte) ([]byte, error) {
+	return DecryptWithAAD(cipherText, key, nil)
+}
+
+// DecryptWithAAD decrypts cipherText with additional data using AES GCM mode.
+func DecryptWithAAD(cipherText, key, aad []byte) ([]byte, error) {
+	gcm, err := NewAEAD(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	nonceSize := gcm.NonceSize()
 	if len(cipherText) < nonceSize {
-		return "", fmt.Errorf("cipherText is too short: textsize=[%d], noncesize=[%d]", len(cipherText), nonceSize)
+		return nil, fmt.Errorf("cipherText is too short: textsize=[%d], noncesize=[%d]", len(cipherText), nonceSize)
 	}
 
 	nonce := cipherText[:nonceSize]
-	plainByte, err := gcm.Open(nil, nonce, cipherText[nonceSize:], nil)
-	if err != nil {
-		return "", err
-	}
-
-	return string(plainByte), nil
+	return gcm.Open(nil, nonce, cipherText[nonceSize:], aad)
 }
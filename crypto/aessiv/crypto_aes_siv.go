@@ -0,0 +1,212 @@
+package aessiv
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+const (
+	// KeySize is the key size of AES-256-SIV, which is split into CMAC key and CTR key.
+	KeySize = 64
+	// TagSize is the size of synthetic IV prepended to the cipherText.
+	TagSize = aes.BlockSize
+)
+
+var errOpen = errors.New("cipher: message authentication failed")
+
+// Encrypt encrypts plainText using AES-SIV mode.
+// The result is deterministic, the same plainText and key produce the same cipherText.
+func Encrypt(plainText string, key []byte) ([]byte, error) {
+	return EncryptBytes([]byte(plainText), key)
+}
+
+// EncryptBytes encrypts plainText using AES-SIV mode.
+func EncryptBytes(plainText, key []byte) ([]byte, error) {
+	return Seal(key, plainText)
+}
+
+// EncryptWithAAD encrypts plainText with additional data using AES-SIV mode.
+// The same additional data is required for decryption.
+// Empty aad is the same as EncryptBytes.
+func EncryptWithAAD(plainText, key, aad []byte) ([]byte, error) {
+	return Seal(key, plainText, adVector(aad)...)
+}
+
+// Decrypt decrypts cipherText using AES-SIV mode.
+func Decrypt(cipherText, key []byte) (string, error) {
+	plainByte, err := DecryptBytes(cipherText, key)
+	return string(plainByte), err
+}
+
+// DecryptBytes decrypts cipherText using AES-SIV mode.
+func DecryptBytes(cipherText, key []byte) ([]byte, error) {
+	return Open(key, cipherText)
+}
+
+// DecryptWithAAD decrypts cipherText with additional data using AES-SIV mode.
+func DecryptWithAAD(cipherText, key, aad []byte) ([]byte, error) {
+	return Open(key, cipherText, adVector(aad)...)
+}
+
+// adVector returns associated data vector of aad.
+func adVector(aad []byte) [][]byte {
+	if len(aad) == 0 {
+		return nil
+	}
+	return [][]byte{aad}
+}
+
+// Seal encrypts plainText with associated data vector (RFC 5297).
+// The key must be 32, 48 or 64 bytes, and the first 64 bytes are used when it's longer.
+// The result is `V || C`.
+func Seal(key, plainText []byte, ad ...[]byte) ([]byte, error) {
+	macBlock, ctrBlock, err := newBlocks(key)
+	if err != nil {
+		return nil, err
+	}
+
+	v := s2v(macBlock, plainText, ad)
+	cipherText := make([]byte, TagSize+len(plainText))
+	copy(cipherText, v)
+	ctr(ctrBlock, v, cipherText[TagSize:], plainText)
+	return cipherText, nil
+}
+
+// Open decrypts cipherText created by Seal.
+func Open(key, cipherText []byte, ad ...[]byte) ([]byte, error) {
+	if len(cipherText) < TagSize {
+		return nil, fmt.Errorf("cipherText is too short: textsize=[%d], tagsize=[%d]", len(cipherText), TagSize)
+	}
+	macBlock, ctrBlock, err := newBlocks(key)
+	if err != nil {
+		return nil, err
+	}
+
+	v := cipherText[:TagSize]
+	plainText := make([]byte, len(cipherText)-TagSize)
+	ctr(ctrBlock, v, plainText, cipherText[TagSize:])
+
+	expected := s2v(macBlock, plainText, ad)
+	if subtle.ConstantTimeCompare(v, expected) != 1 {
+		for i := range plainText {
+			plainText[i] = 0
+		}
+		return nil, errOpen
+	}
+	return plainText, nil
+}
+
+// newBlocks splits the key and returns AES blocks for CMAC and CTR.
+func newBlocks(key []byte) (macBlock, ctrBlock cipher.Block, err error) {
+	// use first 64byte if the key length is longer than 64byte.
+	if len(key) > KeySize {
+		key = key[0:KeySize]
+	}
+
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, nil, fmt.Errorf("aessiv: invalid key size %d", len(key))
+	}
+
+	half := len(key) / 2
+	macBlock, err = aes.NewCipher(key[:half])
+	if err != nil {
+		return nil, nil, err
+	}
+	ctrBlock, err = aes.NewCipher(key[half:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return macBlock, ctrBlock, nil
+}
+
+// ctr encrypts src by AES-CTR mode with the synthetic IV, whose 31st and 63rd bits are cleared.
+func ctr(block cipher.Block, v, dst, src []byte) {
+	iv := make([]byte, TagSize)
+	copy(iv, v)
+	iv[8] &= 0x7f
+	iv[12] &= 0x7f
+	cipher.NewCTR(block, iv).XORKeyStream(dst, src)
+}
+
+// s2v returns synthetic IV from associated data and plainText (RFC 5297 Section 2.4).
+func s2v(block cipher.Block, plainText []byte, ad [][]byte) []byte {
+	d := cmac(block, make([]byte, aes.BlockSize))
+	for _, v := range ad {
+		d = dbl(d)
+		xorBytes(d, cmac(block, v))
+	}
+
+	var t []byte
+	if len(plainText) >= aes.BlockSize {
+		t = append([]byte{}, plainText...)
+		xorBytes(t[len(t)-aes.BlockSize:], d)
+	} else {
+		t = dbl(d)
+		xorBytes(t, pad(plainText))
+	}
+	return cmac(block, t)
+}
+
+// cmac returns AES-CMAC of the message (RFC 4493).
+func cmac(block cipher.Block, msg []byte) []byte {
+	k1 := make([]byte, aes.BlockSize)
+	block.Encrypt(k1, k1)
+	k1 = dbl(k1)
+	k2 := dbl(k1)
+
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	var last []byte
+	if n == 0 || len(msg)%aes.BlockSize != 0 {
+		if n == 0 {
+			n = 1
+		}
+		last = pad(msg[(n-1)*aes.BlockSize:])
+		xorBytes(last, k2)
+	} else {
+		last = append([]byte{}, msg[(n-1)*aes.BlockSize:]...)
+		xorBytes(last, k1)
+	}
+
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		xorBytes(x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(x, x)
+	}
+	xorBytes(x, last)
+	block.Encrypt(x, x)
+	return x
+}
+
+// dbl returns the block multiplied by x in GF(2^128).
+func dbl(b []byte) []byte {
+	result := make([]byte, aes.BlockSize)
+	var carry byte
+	for i := aes.BlockSize - 1; i >= 0; i-- {
+		result[i] = b[i]<<1 | carry
+		carry = b[i] >> 7
+	}
+	if carry != 0 {
+		result[aes.BlockSize-1] ^= 0x87
+	}
+	return result
+}
+
+// pad returns the block padded by 10*.
+func pad(b []byte) []byte {
+	result := make([]byte, aes.BlockSize)
+	copy(result, b)
+	result[len(b)] = 0x80
+	return result
+}
+
+// xorBytes sets dst to dst xor src.
+func xorBytes(dst, src []byte) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
@@ -0,0 +1,153 @@
+package aessiv
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeal(t *testing.T) {
+	a := assert.New(t)
+
+	// RFC 5297 Appendix A
+	tests := []struct {
+		key       string
+		ad        []string
+		plainText string
+		expected  string
+	}{
+		{
+			key:       "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+			ad:        []string{"101112131415161718191a1b1c1d1e1f2021222324252627"},
+			plainText: "112233445566778899aabbccddee",
+			expected:  "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c",
+		},
+		{
+			key: "7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f",
+			ad: []string{
+				"00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100",
+				"102030405060708090a0",
+				"09f911029d74e35bd84156c5635688c0",
+			},
+			plainText: "7468697320697320736f6d6520706c61696e7465787420746f20656e6372797074207573696e67205349562d414553",
+			expected:  "7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe404326601965c889bf17dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d",
+		},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		ad := make([][]byte, len(tt.ad))
+		for i, v := range tt.ad {
+			ad[i] = mustDecodeHex(v)
+		}
+
+		result, err := Seal(mustDecodeHex(tt.key), mustDecodeHex(tt.plainText), ad...)
+		a.NoError(err, target)
+		a.Equal(tt.expected, hex.EncodeToString(result), target)
+
+		plainText, err := Open(mustDecodeHex(tt.key), result, ad...)
+		a.NoError(err, target)
+		a.Equal(tt.plainText, hex.EncodeToString(plainText), target)
+
+		_, err = Open(mustDecodeHex(tt.key), result, ad[1:]...)
+		a.EqualError(err, "cipher: message authentication failed", target)
+	}
+}
+
+func TestSIV(t *testing.T) {
+	a := assert.New(t)
+	validKey := strings.Repeat("1234567890123456", 4) // 64byte
+	invalidKey := "X" + validKey[1:]
+	shortKey := "too short"
+	longKey := validKey + "XYZ" // 67byte
+
+	tests := []struct {
+		text string
+	}{
+		{"a"},
+		{"aaa"},
+		{"あいうえお"},
+		{"0123456789abcdef"},
+		{""},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		_, err := Encrypt(tt.text, []byte(shortKey))
+		a.EqualError(err, "aessiv: invalid key size 9", target)
+
+		// encryption is deterministic.
+		cipher1, err := Encrypt(tt.text, []byte(validKey))
+		a.NoError(err, target)
+		cipher1Again, err := Encrypt(tt.text, []byte(validKey))
+		a.NoError(err, target)
+		a.Equal(cipher1, cipher1Again, target)
+
+		cipher2, err := Encrypt(tt.text, []byte(invalidKey))
+		a.NoError(err, target)
+		a.NotEqual(cipher1, cipher2, target, "using invalid key")
+
+		// decryption
+		plainText1, err := Decrypt(cipher1, []byte(validKey))
+		a.NoError(err, target)
+		a.Equal(tt.text, plainText1, target)
+
+		_, err = Decrypt(cipher1, []byte(invalidKey))
+		a.EqualError(err, "cipher: message authentication failed", target)
+
+		// long key should be used as first 64byte key.
+		plainTextLongKey, err := Decrypt(cipher1, []byte(longKey))
+		a.NoError(err, target)
+		a.Equal(tt.text, plainTextLongKey, target)
+	}
+
+	_, err := Decrypt([]byte("short"), []byte(validKey))
+	a.EqualError(err, "cipherText is too short: textsize=[5], tagsize=[16]")
+}
+
+func TestEncryptWithAAD(t *testing.T) {
+	a := assert.New(t)
+	key := []byte(strings.Repeat("1234567890123456", 4))
+
+	tests := []struct {
+		aad      string
+		otherAAD string
+	}{
+		{"users.ssn.1", "users.ssn.2"},
+		{"users.ssn.1", ""},
+		{"", "users.ssn.1"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		cipherText, err := EncryptWithAAD([]byte("plain text"), key, []byte(tt.aad))
+		a.NoError(err, target)
+
+		plainText, err := DecryptWithAAD(cipherText, key, []byte(tt.aad))
+		a.NoError(err, target)
+		a.Equal("plain text", string(plainText), target)
+
+		_, err = DecryptWithAAD(cipherText, key, []byte(tt.otherAAD))
+		a.EqualError(err, "cipher: message authentication failed", target)
+	}
+
+	// empty aad is the same as EncryptBytes.
+	cipher1, err := EncryptWithAAD([]byte("plain text"), key, nil)
+	a.NoError(err)
+	cipher2, err := EncryptBytes([]byte("plain text"), key)
+	a.NoError(err)
+	a.Equal(cipher1, cipher2)
+}
+
+func mustDecodeHex(s string) []byte {
+	byt, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return byt
+}
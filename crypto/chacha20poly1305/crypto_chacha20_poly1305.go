@@ -1,6 +1,7 @@
 package chacha20poly1305
 
 import (
+	"crypto/cipher"
 	"crypto/rand"
 	"fmt"
 
@@ -14,47 +15,63 @@ const (
 
 // Encrypt encrypts plainText using XChaCha20-Poly1305.
 func Encrypt(plainText string, key []byte) ([]byte, error) {
+	return EncryptBytes([]byte(plainText), key)
+}
+
+// NewAEAD returns XChaCha20-Poly1305 AEAD.
+func NewAEAD(key []byte) (cipher.AEAD, error) {
 	// use first 32byte if the key length is longer than 32byte.
 	if len(key) > KeySize {
 		key = key[0:KeySize]
 	}
+	return chacha20poly1305.NewX(key)
+}
+
+// EncryptBytes encrypts plainText using XChaCha20-Poly1305.
+func EncryptBytes(plainText, key []byte) ([]byte, error) {
+	return EncryptWithAAD(plainText, key, nil)
+}
 
-	aead, err := chacha20poly1305.NewX(key)
+// EncryptWithAAD encrypts plainText with additional data using XChaCha20-Poly1305.
+// The same additional data is required for decryption.
+func EncryptWithAAD(plainText, key, aad []byte) ([]byte, error) {
+	aead, err := NewAEAD(key)
 	if err != nil {
 		return nil, err
 	}
 
-	nonce := make([]byte, nonceSizeX)
+	// allocate the result buffer at once, nonce is prepended to the sealed text.
+	nonce := make([]byte, nonceSizeX, nonceSizeX+len(plainText)+aead.Overhead())
 	if _, err := rand.Read(nonce); err != nil {
 		return nil, err
 	}
 
-	cipherText := aead.Seal(nil, nonce, []byte(plainText), nil)
-	cipherText = append(nonce, cipherText...)
+	cipherText := aead.Seal(nonce, nonce, plainText, aad)
 	return cipherText, nil
 }
 
 // Decrypt decrypts cipherText using XChaCha20-Poly1305.
 func Decrypt(cipherText, key []byte) (string, error) {
-	// use first 32byte if the key length is longer than 32byte.
-	if len(key) > KeySize {
-		key = key[0:KeySize]
-	}
+	plainByte, err := DecryptBytes(cipherText, key)
+	return string(plainByte), err
+}
+
+// DecryptBytes decrypts cipherText using XChaCha20-Poly1305.
+func DecryptBytes(cipherText, key []byte) ([]byte, error) {
+	return DecryptWithAAD(cipherText, key, nil)
+}
 
-	aead, err := chacha20poly1305.NewX(key)
+// DecryptWithAAD decrypts cipherText with additional data using XChaCha20-Poly1305.
+func DecryptWithAAD(cipherText, key, aad []byte) ([]byte, error) {
+	aead, err := NewAEAD(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if len(cipherText) < nonceSizeX {
-		return "", fmt.Errorf("cipherText is too short: textsize=[%d], noncesize=[%d]", len(cipherText), nonceSizeX)
+		return nil, fmt.Errorf("cipherText is too short: textsize=[%d], noncesize=[%d]", len(cipherText), nonceSizeX)
 	}
 
 	nonce := cipherText[:nonceSizeX]
-	plainByte, err := aead.Open(nil, nonce, cipherText[nonceSizeX:], nil)
-	if err != nil {
-		return "", err
-	}
-
-	return string(plainByte), nil
+	return aead.Open(nil, nonce, cipherText[nonceSizeX:], aad)
 }
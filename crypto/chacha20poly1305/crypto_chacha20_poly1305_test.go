@@ -59,3 +59,31 @@ func TestChaCha(t *testing.T) {
 		a.Equal(tt.text, plainText2, target)
 	}
 }
+
+func TestEncryptWithAAD(t *testing.T) {
+	a := assert.New(t)
+	key := []byte("12345678901234567890123456789012")
+
+	tests := []struct {
+		aad      string
+		otherAAD string
+	}{
+		{"users.ssn.1", "users.ssn.2"},
+		{"users.ssn.1", ""},
+		{"", "users.ssn.1"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		cipherText, err := EncryptWithAAD([]byte("plain text"), key, []byte(tt.aad))
+		a.NoError(err, target)
+
+		plainText, err := DecryptWithAAD(cipherText, key, []byte(tt.aad))
+		a.NoError(err, target)
+		a.Equal("plain text", string(plainText), target)
+
+		_, err = DecryptWithAAD(cipherText, key, []byte(tt.otherAAD))
+		a.EqualError(err, "chacha20poly1305: message authentication failed", target)
+	}
+}
@@ -0,0 +1,151 @@
+package fpe
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Alphabets of numeral strings.
+const (
+	Digits            = "0123456789"
+	LowerAlphanumeric = "0123456789abcdefghijklmnopqrstuvwxyz"
+	Alphanumeric      = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+const (
+	maxRadix    = 1 << 16
+	minDomain   = 1000000 // radix^minlen >= 1,000,000
+	maxTextSize = 1 << 16
+)
+
+// Cipher is interface for format-preserving encryption.
+// The result has the same length and alphabet as the text.
+type Cipher interface {
+	Encrypt(text string, tweak []byte) (string, error)
+	Decrypt(text string, tweak []byte) (string, error)
+	Alphabet() string
+}
+
+// alphabet maps characters and numerals.
+type alphabet struct {
+	chars   []rune
+	indexes map[rune]int
+}
+
+// newAlphabet creates alphabet from the characters.
+func newAlphabet(chars string) (alphabet, error) {
+	a := alphabet{
+		chars:   []rune(chars),
+		indexes: make(map[rune]int),
+	}
+	if len(a.chars) < 2 || len(a.chars) > maxRadix {
+		return alphabet{}, fmt.Errorf("fpe: invalid radix %d", len(a.chars))
+	}
+	for i, r := range a.chars {
+		if _, ok := a.indexes[r]; ok {
+			return alphabet{}, errors.New("fpe: alphabet has duplicate character")
+		}
+		a.indexes[r] = i
+	}
+	return a, nil
+}
+
+// radix returns the base of numerals.
+func (a alphabet) radix() int {
+	return len(a.chars)
+}
+
+// toNumerals converts the text to numerals.
+func (a alphabet) toNumerals(text string) ([]int, error) {
+	numerals := make([]int, 0, len(text))
+	for _, r := range text {
+		v, ok := a.indexes[r]
+		if !ok {
+			return nil, errors.New("fpe: text has character which is not in the alphabet")
+		}
+		numerals = append(numerals, v)
+	}
+	return numerals, nil
+}
+
+// toText converts the numerals to text.
+func (a alphabet) toText(numerals []int) string {
+	var b strings.Builder
+	for _, v := range numerals {
+		b.WriteRune(a.chars[v])
+	}
+	return b.String()
+}
+
+// minLength returns minimum length of numeral string, which satisfies radix^minlen >= 1,000,000.
+func minLength(radix int) int {
+	n := 1
+	for v := radix; v < minDomain; v *= radix {
+		n++
+	}
+	return n
+}
+
+// num returns the number of the numerals, whose first numeral is the most significant.
+func num(numerals []int, radix int) *big.Int {
+	r := big.NewInt(int64(radix))
+	x := new(big.Int)
+	for _, v := range numerals {
+		x.Mul(x, r)
+		x.Add(x, big.NewInt(int64(v)))
+	}
+	return x
+}
+
+// str returns m numerals of x, whose first numeral is the most significant.
+func str(x *big.Int, radix, m int) []int {
+	r := big.NewInt(int64(radix))
+	x = new(big.Int).Set(x)
+	mod := new(big.Int)
+	numerals := make([]int, m)
+	for i := m - 1; i >= 0; i-- {
+		x.DivMod(x, r, mod)
+		numerals[i] = int(mod.Int64())
+	}
+	return numerals
+}
+
+// pow returns radix^m.
+func pow(radix, m int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(int64(radix)), big.NewInt(int64(m)), nil)
+}
+
+// EncryptFormatted encrypts characters in the alphabet of c, and keeps other characters (e.g. separators) as is.
+// (e.g. `123-45-6789` -> `862-17-0384` by Digits)
+func EncryptFormatted(c Cipher, text string, tweak []byte) (string, error) {
+	return transformFormatted(c.Encrypt, c.Alphabet(), text, tweak)
+}
+
+// DecryptFormatted decrypts text created by EncryptFormatted.
+func DecryptFormatted(c Cipher, text string, tweak []byte) (string, error) {
+	return transformFormatted(c.Decrypt, c.Alphabet(), text, tweak)
+}
+
+// transformFormatted applies fn to characters in the alphabet, and puts them back to the original positions.
+func transformFormatted(fn func(string, []byte) (string, error), chars, text string, tweak []byte) (string, error) {
+	runes := []rune(text)
+	var positions []int
+	var b strings.Builder
+	for i, r := range runes {
+		if strings.ContainsRune(chars, r) {
+			positions = append(positions, i)
+			b.WriteRune(r)
+		}
+	}
+
+	result, err := fn(b.String(), tweak)
+	if err != nil {
+		return "", err
+	}
+	for i, r := range []rune(result) {
+		runes[positions[i]] = r
+	}
+	return string(runes), nil
+}
@@ -0,0 +1,76 @@
+package fpe
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptFormatted(t *testing.T) {
+	a := assert.New(t)
+	key := mustDecodeHex("2B7E151628AED2A6ABF7158809CF4F3C")
+
+	ff1, err := NewFF1(key, Digits)
+	a.NoError(err)
+	ff3, err := NewFF3(key, Digits)
+	a.NoError(err)
+
+	tests := []struct {
+		cipher Cipher
+		text   string
+		shape  string
+	}{
+		{ff1, "123-45-6789", "###-##-####"},
+		{ff1, "+81 90-1234-5678", "+## ##-####-####"},
+		{ff3, "123-45-6789", "###-##-####"},
+		{ff3, "4111 1111 1111 1111", "#### #### #### ####"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		tweak := make([]byte, FF3TweakSize)
+
+		result, err := EncryptFormatted(tt.cipher, tt.text, tweak)
+		a.NoError(err, target)
+		a.NotEqual(tt.text, result, target)
+		a.Equal(tt.shape, toShape(result), target)
+
+		plainText, err := DecryptFormatted(tt.cipher, result, tweak)
+		a.NoError(err, target)
+		a.Equal(tt.text, plainText, target)
+	}
+
+	_, err = EncryptFormatted(ff1, "12-34", nil)
+	a.EqualError(err, "fpe: invalid text length 4")
+}
+
+func TestMinLength(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		radix    int
+		expected int
+	}{
+		{2, 20},
+		{10, 6},
+		{36, 4},
+		{62, 4},
+		{1 << 16, 2},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, minLength(tt.radix), target)
+	}
+}
+
+func toShape(text string) string {
+	shape := []rune(text)
+	for i, r := range shape {
+		if r >= '0' && r <= '9' {
+			shape[i] = '#'
+		}
+	}
+	return string(shape)
+}
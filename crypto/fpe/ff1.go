@@ -0,0 +1,152 @@
+package fpe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+const ff1Rounds = 10
+
+// FF1 is format-preserving encryption FF1 (NIST SP 800-38G).
+// Tweak has arbitrary length.
+type FF1 struct {
+	block    cipher.Block
+	alphabet alphabet
+	minLen   int
+}
+
+// NewFF1 creates FF1 cipher of the alphabet.
+// The key must be 16, 24 or 32 bytes of AES.
+func NewFF1(key []byte, chars string) (*FF1, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	a, err := newAlphabet(chars)
+	if err != nil {
+		return nil, err
+	}
+	return &FF1{
+		block:    block,
+		alphabet: a,
+		minLen:   minLength(a.radix()),
+	}, nil
+}
+
+// Alphabet returns characters of numerals.
+func (c *FF1) Alphabet() string {
+	return string(c.alphabet.chars)
+}
+
+// Encrypt encrypts the text with the tweak.
+func (c *FF1) Encrypt(text string, tweak []byte) (string, error) {
+	return c.transform(text, tweak, true)
+}
+
+// Decrypt decrypts the text with the tweak.
+func (c *FF1) Decrypt(text string, tweak []byte) (string, error) {
+	return c.transform(text, tweak, false)
+}
+
+// transform runs Feistel rounds of FF1.
+func (c *FF1) transform(text string, tweak []byte, encrypt bool) (string, error) {
+	x, err := c.alphabet.toNumerals(text)
+	if err != nil {
+		return "", err
+	}
+	n := len(x)
+	if n < c.minLen || n > maxTextSize {
+		return "", fmt.Errorf("fpe: invalid text length %d", n)
+	}
+
+	radix := c.alphabet.radix()
+	u := n / 2
+	v := n - u
+	a := num(x[:u], radix)
+	b := num(x[u:], radix)
+
+	bSize := int(math.Ceil(math.Ceil(float64(v)*math.Log2(float64(radix))) / 8))
+	dSize := 4*((bSize+3)/4) + 4
+
+	p := make([]byte, aes.BlockSize)
+	p[0], p[1], p[2] = 1, 2, 1
+	p[3], p[4], p[5] = byte(radix>>16), byte(radix>>8), byte(radix)
+	p[6] = 10
+	p[7] = byte(u)
+	binary.BigEndian.PutUint32(p[8:12], uint32(n))
+	binary.BigEndian.PutUint32(p[12:16], uint32(len(tweak)))
+
+	padSize := (aes.BlockSize - (len(tweak)+bSize+1)%aes.BlockSize) % aes.BlockSize
+	q := make([]byte, len(tweak)+padSize+1+bSize)
+	copy(q, tweak)
+
+	modU := pow(radix, u)
+	modV := pow(radix, v)
+	for j := 0; j < ff1Rounds; j++ {
+		i := j
+		if !encrypt {
+			i = ff1Rounds - 1 - j
+		}
+		mod := modU
+		if i%2 == 1 {
+			mod = modV
+		}
+
+		// Q = T || 0 || i || NUM(B) on encryption, NUM(A) on decryption.
+		q[len(tweak)+padSize] = byte(i)
+		input := b
+		if !encrypt {
+			input = a
+		}
+		putBigInt(q[len(q)-bSize:], input)
+
+		y := new(big.Int).SetBytes(c.expand(c.prf(p, q), dSize))
+		if encrypt {
+			y.Add(a, y)
+			a, b = b, y.Mod(y, mod)
+		} else {
+			y.Sub(b, y)
+			b, a = a, y.Mod(y, mod)
+		}
+	}
+	return c.alphabet.toText(append(str(a, radix, u), str(b, radix, v)...)), nil
+}
+
+// prf returns CBC-MAC of P || Q with zero IV.
+func (c *FF1) prf(p, q []byte) []byte {
+	y := make([]byte, aes.BlockSize)
+	for _, data := range [][]byte{p, q} {
+		for i := 0; i < len(data); i += aes.BlockSize {
+			for k := 0; k < aes.BlockSize; k++ {
+				y[k] ^= data[i+k]
+			}
+			c.block.Encrypt(y, y)
+		}
+	}
+	return y
+}
+
+// expand returns d bytes of R || CIPH(R xor [1]) || CIPH(R xor [2]) ...
+func (c *FF1) expand(r []byte, d int) []byte {
+	s := append([]byte{}, r...)
+	for j := 1; len(s) < d; j++ {
+		block := append([]byte{}, r...)
+		binary.BigEndian.PutUint64(block[8:], binary.BigEndian.Uint64(block[8:])^uint64(j))
+		c.block.Encrypt(block, block)
+		s = append(s, block...)
+	}
+	return s[:d]
+}
+
+// putBigInt writes x into dst as big-endian, left-padded by zero.
+func putBigInt(dst []byte, x *big.Int) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	byt := x.Bytes()
+	copy(dst[len(dst)-len(byt):], byt)
+}
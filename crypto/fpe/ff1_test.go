@@ -0,0 +1,81 @@
+package fpe
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFF1(t *testing.T) {
+	a := assert.New(t)
+
+	// NIST SP 800-38G samples
+	tests := []struct {
+		key       string
+		alphabet  string
+		tweak     string
+		plainText string
+		expected  string
+	}{
+		{"2B7E151628AED2A6ABF7158809CF4F3C", Digits, "", "0123456789", "2433477484"},
+		{"2B7E151628AED2A6ABF7158809CF4F3C", Digits, "39383736353433323130", "0123456789", "6124200773"},
+		{"2B7E151628AED2A6ABF7158809CF4F3C", LowerAlphanumeric, "3737373770717273373737", "0123456789abcdefghi", "a9tv40mll9kdu509eum"},
+		{"2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94", Digits, "", "0123456789", "6657667009"},
+		{"2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94", Digits, "39383736353433323130", "0123456789", "1001623463"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		c, err := NewFF1(mustDecodeHex(tt.key), tt.alphabet)
+		a.NoError(err, target)
+
+		result, err := c.Encrypt(tt.plainText, mustDecodeHex(tt.tweak))
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+
+		plainText, err := c.Decrypt(result, mustDecodeHex(tt.tweak))
+		a.NoError(err, target)
+		a.Equal(tt.plainText, plainText, target)
+	}
+}
+
+func TestFF1Error(t *testing.T) {
+	a := assert.New(t)
+	key := mustDecodeHex("2B7E151628AED2A6ABF7158809CF4F3C")
+
+	_, err := NewFF1(key[:15], Digits)
+	a.EqualError(err, "crypto/aes: invalid key size 15")
+	_, err = NewFF1(key, "0")
+	a.EqualError(err, "fpe: invalid radix 1")
+	_, err = NewFF1(key, "00")
+	a.EqualError(err, "fpe: alphabet has duplicate character")
+
+	c, err := NewFF1(key, Digits)
+	a.NoError(err)
+
+	tests := []struct {
+		text       string
+		errMessage string
+	}{
+		{"12345", "fpe: invalid text length 5"},
+		{"12345a", "fpe: text has character which is not in the alphabet"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		_, err := c.Encrypt(tt.text, nil)
+		a.EqualError(err, tt.errMessage, target)
+		_, err = c.Decrypt(tt.text, nil)
+		a.EqualError(err, tt.errMessage, target)
+	}
+}
+
+func mustDecodeHex(s string) []byte {
+	byt, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return byt
+}
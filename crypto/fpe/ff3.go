@@ -0,0 +1,153 @@
+package fpe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+const (
+	ff3Rounds = 8
+	// FF3TweakSize is the tweak size of FF3-1, 56 bits.
+	FF3TweakSize = 7
+)
+
+// FF3 is format-preserving encryption FF3-1 (NIST SP 800-38G Rev.1).
+// Tweak must be 7 bytes.
+type FF3 struct {
+	block    cipher.Block
+	alphabet alphabet
+	minLen   int
+	maxLen   int
+}
+
+// NewFF3 creates FF3-1 cipher of the alphabet.
+// The key must be 16, 24 or 32 bytes of AES.
+func NewFF3(key []byte, chars string) (*FF3, error) {
+	// FF3 uses byte-reversed key.
+	reversed := make([]byte, len(key))
+	for i := range key {
+		reversed[len(key)-1-i] = key[i]
+	}
+	block, err := aes.NewCipher(reversed)
+	if err != nil {
+		return nil, err
+	}
+	a, err := newAlphabet(chars)
+	if err != nil {
+		return nil, err
+	}
+	return &FF3{
+		block:    block,
+		alphabet: a,
+		minLen:   minLength(a.radix()),
+		maxLen:   2 * int(math.Floor(96/math.Log2(float64(a.radix())))),
+	}, nil
+}
+
+// Alphabet returns characters of numerals.
+func (c *FF3) Alphabet() string {
+	return string(c.alphabet.chars)
+}
+
+// Encrypt encrypts the text with the tweak.
+func (c *FF3) Encrypt(text string, tweak []byte) (string, error) {
+	tl, tr, err := splitTweak(tweak)
+	if err != nil {
+		return "", err
+	}
+	return c.transform(text, tl, tr, true)
+}
+
+// Decrypt decrypts the text with the tweak.
+func (c *FF3) Decrypt(text string, tweak []byte) (string, error) {
+	tl, tr, err := splitTweak(tweak)
+	if err != nil {
+		return "", err
+	}
+	return c.transform(text, tl, tr, false)
+}
+
+// splitTweak splits 56 bits tweak of FF3-1 into 32 bits TL and TR.
+func splitTweak(tweak []byte) (tl, tr []byte, err error) {
+	if len(tweak) != FF3TweakSize {
+		return nil, nil, fmt.Errorf("fpe: invalid tweak size %d", len(tweak))
+	}
+	tl = []byte{tweak[0], tweak[1], tweak[2], tweak[3] & 0xf0}
+	tr = []byte{tweak[4], tweak[5], tweak[6], tweak[3] << 4}
+	return tl, tr, nil
+}
+
+// transform runs Feistel rounds of FF3 with 32 bits TL and TR.
+func (c *FF3) transform(text string, tl, tr []byte, encrypt bool) (string, error) {
+	x, err := c.alphabet.toNumerals(text)
+	if err != nil {
+		return "", err
+	}
+	n := len(x)
+	if n < c.minLen || n > c.maxLen {
+		return "", fmt.Errorf("fpe: invalid text length %d", n)
+	}
+
+	radix := c.alphabet.radix()
+	u := (n + 1) / 2
+	v := n - u
+	// numerals are reversed in FF3.
+	a := num(reverse(x[:u]), radix)
+	b := num(reverse(x[u:]), radix)
+
+	modU := pow(radix, u)
+	modV := pow(radix, v)
+	p := make([]byte, aes.BlockSize)
+	for j := 0; j < ff3Rounds; j++ {
+		i := j
+		if !encrypt {
+			i = ff3Rounds - 1 - j
+		}
+		w, mod := tr, modU
+		if i%2 == 1 {
+			w, mod = tl, modV
+		}
+
+		// P = W xor [i] || NUM(REV(B)) on encryption, NUM(REV(A)) on decryption.
+		copy(p, w)
+		p[3] ^= byte(i)
+		input := b
+		if !encrypt {
+			input = a
+		}
+		putBigInt(p[4:], input)
+
+		reverseBytes(p)
+		c.block.Encrypt(p, p)
+		reverseBytes(p)
+
+		y := new(big.Int).SetBytes(p)
+		if encrypt {
+			y.Add(a, y)
+			a, b = b, y.Mod(y, mod)
+		} else {
+			y.Sub(b, y)
+			b, a = a, y.Mod(y, mod)
+		}
+	}
+	return c.alphabet.toText(append(reverse(str(a, radix, u)), reverse(str(b, radix, v))...)), nil
+}
+
+// reverse returns reversed numerals.
+func reverse(numerals []int) []int {
+	result := make([]int, len(numerals))
+	for i, v := range numerals {
+		result[len(numerals)-1-i] = v
+	}
+	return result
+}
+
+// reverseBytes reverses byt in place.
+func reverseBytes(byt []byte) {
+	for i, j := 0, len(byt)-1; i < j; i, j = i+1, j-1 {
+		byt[i], byt[j] = byt[j], byt[i]
+	}
+}
@@ -0,0 +1,98 @@
+package fpe
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFF3(t *testing.T) {
+	a := assert.New(t)
+
+	// NIST SP 800-38G samples of FF3, which has 64 bits tweak.
+	tests := []struct {
+		key       string
+		alphabet  string
+		tweak     string
+		plainText string
+		expected  string
+	}{
+		{"EF4359D8D580AA4F7F036D6F04FC6A94", Digits, "D8E7920AFA330A73", "890121234567890000", "750918814058654607"},
+		{"EF4359D8D580AA4F7F036D6F04FC6A94", Digits, "9A768A92F60E12D8", "890121234567890000", "018989839189395384"},
+		{"EF4359D8D580AA4F7F036D6F04FC6A94", Digits, "D8E7920AFA330A73", "89012123456789000000789000000", "48598367162252569629397416226"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		c, err := NewFF3(mustDecodeHex(tt.key), tt.alphabet)
+		a.NoError(err, target)
+
+		tweak := mustDecodeHex(tt.tweak)
+		result, err := c.transform(tt.plainText, tweak[:4], tweak[4:], true)
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+
+		plainText, err := c.transform(result, tweak[:4], tweak[4:], false)
+		a.NoError(err, target)
+		a.Equal(tt.plainText, plainText, target)
+	}
+}
+
+func TestFF3_1(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		key       string
+		alphabet  string
+		tweak     string
+		plainText string
+		expected  string
+	}{
+		{"2DE79D232DF5585D68CE47882AE256D6", Digits, "CBD09280979564", "3992520240", "8901801106"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		c, err := NewFF3(mustDecodeHex(tt.key), tt.alphabet)
+		a.NoError(err, target)
+
+		result, err := c.Encrypt(tt.plainText, mustDecodeHex(tt.tweak))
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+
+		plainText, err := c.Decrypt(result, mustDecodeHex(tt.tweak))
+		a.NoError(err, target)
+		a.Equal(tt.plainText, plainText, target)
+	}
+}
+
+func TestFF3Error(t *testing.T) {
+	a := assert.New(t)
+	key := mustDecodeHex("EF4359D8D580AA4F7F036D6F04FC6A94")
+
+	_, err := NewFF3(key[:15], Digits)
+	a.EqualError(err, "crypto/aes: invalid key size 15")
+
+	c, err := NewFF3(key, Digits)
+	a.NoError(err)
+
+	tests := []struct {
+		text       string
+		tweak      []byte
+		errMessage string
+	}{
+		{"123456", make([]byte, 8), "fpe: invalid tweak size 8"},
+		{"12345", make([]byte, 7), "fpe: invalid text length 5"},
+		{"12345678901234567890123456789012345678901234567890123456789", make([]byte, 7), "fpe: invalid text length 59"},
+		{"12345a", make([]byte, 7), "fpe: text has character which is not in the alphabet"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		_, err := c.Encrypt(tt.text, tt.tweak)
+		a.EqualError(err, tt.errMessage, target)
+		_, err = c.Decrypt(tt.text, tt.tweak)
+		a.EqualError(err, tt.errMessage, target)
+	}
+}
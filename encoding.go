@@ -0,0 +1,68 @@
+package hierogolyph
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Encoding is the text form of the versioned envelope.
+type Encoding int
+
+const (
+	// EncodingBase64 is standard base64 with padding, which is the default.
+	EncodingBase64 Encoding = iota
+	// EncodingBase64URL is compact URL-safe base64 without padding.
+	EncodingBase64URL
+	// EncodingHex is lower case hex.
+	EncodingHex
+	// EncodingBinary is raw bytes for BYTEA/BLOB columns.
+	EncodingBinary
+)
+
+// hexMagic is envelopeMagic in hex.
+var hexMagic = hex.EncodeToString(envelopeMagic)
+
+// encode returns byt in the encoding.
+func (enc Encoding) encode(byt []byte) (string, error) {
+	switch enc {
+	case EncodingBase64:
+		return encodeBase64(byt), nil
+	case EncodingBase64URL:
+		return base64.RawURLEncoding.EncodeToString(byt), nil
+	case EncodingHex:
+		return hex.EncodeToString(byt), nil
+	case EncodingBinary:
+		return string(byt), nil
+	}
+	return "", fmt.Errorf("encoding is unknown: encoding=[%d]", enc)
+}
+
+// decode returns bytes of text in the encoding.
+func (enc Encoding) decode(text string) ([]byte, error) {
+	switch enc {
+	case EncodingBase64URL:
+		return base64.RawURLEncoding.DecodeString(text)
+	case EncodingHex:
+		return hex.DecodeString(text)
+	case EncodingBinary:
+		return []byte(text), nil
+	}
+	return base64.StdEncoding.DecodeString(text)
+}
+
+// detectEncoding returns the encoding of the versioned envelope.
+// Each encoding of the envelope magic has a different prefix. (`HG`, `4847` and `SEc`)
+func detectEncoding(cipherText string) Encoding {
+	switch {
+	case strings.HasPrefix(cipherText, string(envelopeMagic)):
+		return EncodingBinary
+	case strings.HasPrefix(cipherText, hexMagic):
+		return EncodingHex
+	case strings.ContainsAny(cipherText, "-_"),
+		len(cipherText)%4 != 0 && !strings.HasSuffix(cipherText, "="):
+		return EncodingBase64URL
+	}
+	return EncodingBase64
+}
@@ -0,0 +1,94 @@
+package hierogolyph
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	hsmchacha "github.com/evalphobia/hierogolyph/hsm/chacha20poly1305"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHierogolyph_EncryptEncoding(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		encoding Encoding
+		prefix   string
+		decode   func(string) ([]byte, error)
+	}{
+		{EncodingBase64, "SEcB", base64.StdEncoding.DecodeString},
+		{EncodingBase64URL, "SEcB", base64.RawURLEncoding.DecodeString},
+		{EncodingHex, "484701", hex.DecodeString},
+		{EncodingBinary, "HG\x01", func(s string) ([]byte, error) {
+			return []byte(s), nil
+		}},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt.encoding)
+		h, err := CreateHierogolyph("password", testConfig)
+		a.NoError(err, target)
+		h.Config.Encoding = tt.encoding
+
+		for _, text := range []string{"", "plain text", "???>>>"} {
+			cipherText, err := h.Encrypt(text)
+			a.NoError(err, target)
+			a.True(strings.HasPrefix(cipherText, tt.prefix), target)
+			a.Equal(tt.encoding, detectEncoding(cipherText), target)
+			byt, err := tt.decode(cipherText)
+			a.NoError(err, target)
+			_, err = parseEnvelopeBinary(byt)
+			a.NoError(err, target)
+
+			// Decrypt detects the encoding.
+			h2 := h
+			h2.Config.Encoding = EncodingBase64
+			plainText, err := h2.Decrypt(cipherText)
+			a.NoError(err, target)
+			a.Equal(text, plainText, target)
+
+			// the encoding is kept on rotation.
+			rotatedText, err := h.RotateHSMCipherText(cipherText, hsmchacha.NewMockHSM([]byte("12345678901234567890123456789012")))
+			a.NoError(err, target)
+			a.Equal(tt.encoding, detectEncoding(rotatedText), target)
+		}
+	}
+
+	// legacy format
+	h := testHierogolyph1
+	h.Config = testConfig
+	h.Config.Encoding = EncodingHex
+	plainText, err := h.Decrypt(testLegacyCipherText1)
+	a.NoError(err)
+	a.Equal("plain text", plainText)
+
+	h.Config.Encoding = 99
+	_, err = h.Encrypt("plain text")
+	a.EqualError(err, "encoding is unknown: encoding=[99]")
+}
+
+func TestDetectEncoding(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		text     string
+		expected Encoding
+	}{
+		{"", EncodingBase64},
+		{"SEcBAQ==", EncodingBase64},
+		{"SEcB+/AB", EncodingBase64},
+		{"SEcBAQ", EncodingBase64URL},
+		{"SEcB-_AB", EncodingBase64URL},
+		{"48470101", EncodingHex},
+		{"HG\x01.", EncodingBinary},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, detectEncoding(tt.text), target)
+	}
+}
@@ -0,0 +1,61 @@
+package hierogolyph
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Encrypter encrypts and decrypts values, Hierogolyph and *Session implement it.
+type Encrypter interface {
+	Encrypt(plainText string) (cipherText string, err error)
+	Decrypt(cipherText string) (plainText string, err error)
+}
+
+// Redactor is Encrypter which emits RedactedValue instead of cipherText. (e.g. logs and events sent outside)
+// Bind it to opt in the redaction, and RedactedValue is unmarshalled as empty value only when Redactor is bound.
+type Redactor struct{}
+
+// Encrypt returns RedactedValue.
+func (Redactor) Encrypt(plainText string) (cipherText string, err error) {
+	return RedactedValue, nil
+}
+
+// Decrypt returns empty value for RedactedValue, cipherText cannot be decrypted.
+func (Redactor) Decrypt(cipherText string) (plainText string, err error) {
+	if cipherText != RedactedValue {
+		return "", errors.New("redactor cannot decrypt cipherText")
+	}
+	return "", nil
+}
+
+type encrypterKey struct{}
+
+var defaultEncrypter = struct {
+	mu sync.RWMutex
+	e  Encrypter
+}{}
+
+// SetDefaultEncrypter sets Encrypter used by EncryptedString and Encrypted when Encrypter is not bound.
+func SetDefaultEncrypter(e Encrypter) {
+	defaultEncrypter.mu.Lock()
+	defer defaultEncrypter.mu.Unlock()
+	defaultEncrypter.e = e
+}
+
+// ContextWithEncrypter returns ctx which has Encrypter. (e.g. Session of the current user)
+func ContextWithEncrypter(ctx context.Context, e Encrypter) context.Context {
+	return context.WithValue(ctx, encrypterKey{}, e)
+}
+
+// EncrypterFromContext returns Encrypter of ctx, or the default Encrypter when ctx doesn't have it.
+// It returns nil when both of them are not set.
+func EncrypterFromContext(ctx context.Context) Encrypter {
+	if e, ok := ctx.Value(encrypterKey{}).(Encrypter); ok && e != nil {
+		return e
+	}
+
+	defaultEncrypter.mu.RLock()
+	defer defaultEncrypter.mu.RUnlock()
+	return defaultEncrypter.e
+}
@@ -0,0 +1,270 @@
+package hierogolyph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	// EnvelopeVersion0 is legacy format, `base64(EncryptionKey).base64(cipherText)`.
+	EnvelopeVersion0 = 0
+	// EnvelopeVersion1 is binary format with algorithm identifiers.
+	EnvelopeVersion1 = 1
+)
+
+// envelopeMagic is the first bytes of binary envelope.
+var envelopeMagic = []byte("HG")
+
+// field tags of binary envelope.
+const (
+	tagCipher byte = iota + 1
+	tagHasher
+	tagHasherParams
+	tagHSM
+	tagEncryptionKey
+	tagCipherText
+	tagPayload
+	tagSegmentSize
+	tagStreamSalt
+	tagAAD
+	tagEncryptionKeyV1
+	tagDeterministic
+	tagPadding
+)
+
+// Envelope is a self-describing container of encrypted data.
+// It records the format version and algorithms used for the encryption.
+type Envelope struct {
+	Version      int
+	Cipher       string
+	Hasher       string
+	HasherParams string
+	HSM          string
+	Payload      int  // format of decrypted payload, PayloadText, PayloadBinary or PayloadStream.
+	AAD          bool // true when the cipherText is bound to additional authenticated data.
+
+	// Deterministic is true when the same plainText produces the same cipherText. (e.g. AES-SIV)
+	Deterministic bool
+
+	// Padding is the policy used for hiding the length of plainText, Mode is empty when it's not padded.
+	Padding Padding
+
+	EncryptionKey string // same format as Hierogolyph.EncryptionKey.
+	CipherText    []byte
+
+	// used for streaming encryption.
+	SegmentSize int
+	StreamSalt  []byte
+}
+
+// ParseEnvelope parses cipherText created by Hierogolyph.Encrypt.
+// It returns ErrMalformedCipherText when cipherText cannot be parsed.
+// The encoding is detected automatically, and the legacy format (v0) is parsed too.
+func ParseEnvelope(cipherText string) (Envelope, error) {
+	enc := detectEncoding(cipherText)
+	// v0 has a dot, which is not used in base64 and hex.
+	if enc != EncodingBinary && strings.Contains(cipherText, ".") {
+		encryptionKey, encryptedText, err := decodeCipherText(cipherText)
+		if err != nil {
+			return Envelope{}, newError(ErrMalformedCipherText, err)
+		}
+		return Envelope{
+			Version:       EnvelopeVersion0,
+			Payload:       PayloadText,
+			EncryptionKey: encryptionKey,
+			CipherText:    []byte(encryptedText),
+		}, nil
+	}
+
+	byt, err := enc.decode(cipherText)
+	if err != nil {
+		return Envelope{}, newError(ErrMalformedCipherText, err)
+	}
+	e, err := parseEnvelopeBinary(byt)
+	if err != nil {
+		return Envelope{}, newError(ErrMalformedCipherText, err)
+	}
+	return e, nil
+}
+
+// Encode returns text form of the envelope in EncodingBase64.
+func (e Envelope) Encode() (string, error) {
+	return e.EncodeWith(EncodingBase64)
+}
+
+// EncodeWith returns text form of the envelope in the encoding.
+// The legacy format (v0) is always base64.
+func (e Envelope) EncodeWith(enc Encoding) (string, error) {
+	if e.Version == EnvelopeVersion0 {
+		return fmt.Sprintf("%s.%s", encodeBase64String(e.EncryptionKey), encodeBase64(e.CipherText)), nil
+	}
+
+	byt, err := e.marshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return enc.encode(byt)
+}
+
+// withoutWrappedKey returns the envelope which has only the key id instead of the wrapped key.
+// Hasher and HSM are also removed, because they are used only for the wrapped key.
+// So deterministic cipherText is not changed by ChangePassword and RotateHSM.
+func (e Envelope) withoutWrappedKey() Envelope {
+	key, err := parseEncryptionKey(e.EncryptionKey)
+	if err != nil || key.Version != EncryptionKeyVersion1 {
+		// legacy EncryptionKey does not have the key id.
+		return e
+	}
+
+	e.EncryptionKey = newReferenceKey(key.KeyID).String()
+	e.Hasher = ""
+	e.HasherParams = ""
+	e.HSM = ""
+	return e
+}
+
+// marshalBinary returns binary form of the envelope.
+func (e Envelope) marshalBinary() ([]byte, error) {
+	if e.Version != EnvelopeVersion1 {
+		return nil, fmt.Errorf("envelope version=[%d] is not supported", e.Version)
+	}
+
+	// EncryptionKey is text and it's stored as raw bytes to avoid double encoding.
+	key, err := parseEncryptionKey(e.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	byt := append([]byte{}, envelopeMagic...)
+	byt = append(byt, byte(e.Version))
+	byt = appendField(byt, tagCipher, []byte(e.Cipher))
+	byt = appendField(byt, tagHasher, []byte(e.Hasher))
+	byt = appendField(byt, tagHasherParams, []byte(e.HasherParams))
+	byt = appendField(byt, tagHSM, []byte(e.HSM))
+	byt = appendField(byt, tagPayload, []byte{byte(e.Payload)})
+	if key.Version == EncryptionKeyVersion0 {
+		byt = appendField(byt, tagEncryptionKey, key.Masked)
+	} else {
+		byt = appendField(byt, tagEncryptionKeyV1, key.marshalBinary())
+	}
+	byt = appendField(byt, tagCipherText, e.CipherText)
+	if e.AAD {
+		byt = appendField(byt, tagAAD, []byte{1})
+	}
+	if e.Deterministic {
+		byt = appendField(byt, tagDeterministic, []byte{1})
+	}
+	if e.Padding.Mode != "" {
+		byt = appendField(byt, tagPadding, e.Padding.marshalBinary())
+	}
+	if e.Payload == PayloadStream {
+		byt = appendField(byt, tagSegmentSize, encodeUvarint(uint64(e.SegmentSize)))
+		byt = appendField(byt, tagStreamSalt, e.StreamSalt)
+	}
+	return byt, nil
+}
+
+// parseEnvelopeBinary parses binary form of the envelope.
+func parseEnvelopeBinary(byt []byte) (Envelope, error) {
+	headerSize := len(envelopeMagic) + 1
+	if len(byt) < headerSize || !bytes.Equal(byt[:len(envelopeMagic)], envelopeMagic) {
+		return Envelope{}, fmt.Errorf("envelope header is invalid: size=[%d]", len(byt))
+	}
+
+	e := Envelope{
+		Version: int(byt[len(envelopeMagic)]),
+	}
+	if e.Version != EnvelopeVersion1 {
+		return Envelope{}, fmt.Errorf("envelope version=[%d] is not supported", e.Version)
+	}
+
+	rest := byt[headerSize:]
+	for len(rest) > 0 {
+		tag, value, next, err := readField(rest)
+		if err != nil {
+			return Envelope{}, err
+		}
+		rest = next
+
+		switch tag {
+		case tagCipher:
+			e.Cipher = string(value)
+		case tagHasher:
+			e.Hasher = string(value)
+		case tagHasherParams:
+			e.HasherParams = string(value)
+		case tagHSM:
+			e.HSM = string(value)
+		case tagEncryptionKey:
+			e.EncryptionKey = encodeBase64(value)
+		case tagEncryptionKeyV1:
+			key, err := parseEncryptionKeyBinary(value)
+			if err != nil {
+				return Envelope{}, err
+			}
+			e.EncryptionKey = key.String()
+		case tagCipherText:
+			e.CipherText = value
+		case tagPayload:
+			if len(value) != 1 {
+				return Envelope{}, fmt.Errorf("envelope field is broken: tag=[%d]", tag)
+			}
+			e.Payload = int(value[0])
+		case tagSegmentSize:
+			size, n := binary.Uvarint(value)
+			if n != len(value) || size > maxSegmentSize {
+				return Envelope{}, fmt.Errorf("envelope field is broken: tag=[%d]", tag)
+			}
+			e.SegmentSize = int(size)
+		case tagStreamSalt:
+			e.StreamSalt = value
+		case tagAAD:
+			if len(value) != 1 || value[0] != 1 {
+				return Envelope{}, fmt.Errorf("envelope field is broken: tag=[%d]", tag)
+			}
+			e.AAD = true
+		case tagDeterministic:
+			if len(value) != 1 || value[0] != 1 {
+				return Envelope{}, fmt.Errorf("envelope field is broken: tag=[%d]", tag)
+			}
+			e.Deterministic = true
+		case tagPadding:
+			e.Padding, err = parsePaddingBinary(value)
+			if err != nil {
+				return Envelope{}, err
+			}
+		default:
+			return Envelope{}, fmt.Errorf("envelope has unknown field: tag=[%d]", tag)
+		}
+	}
+	return e, nil
+}
+
+// appendField appends a field as tag, length and value.
+func appendField(byt []byte, tag byte, value []byte) []byte {
+	byt = append(byt, tag)
+	byt = append(byt, encodeUvarint(uint64(len(value)))...)
+	return append(byt, value...)
+}
+
+// encodeUvarint returns varint-encoded bytes of v.
+func encodeUvarint(v uint64) []byte {
+	var byt [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(byt[:], v)
+	return byt[:n]
+}
+
+// readField reads a field appended by appendField and returns rest bytes.
+func readField(byt []byte) (tag byte, value, rest []byte, err error) {
+	tag = byt[0]
+	size, n := binary.Uvarint(byt[1:])
+	if n <= 0 || size > uint64(len(byt)-1-n) {
+		return 0, nil, nil, fmt.Errorf("envelope field is broken: tag=[%d]", tag)
+	}
+
+	start := 1 + n
+	end := start + int(size)
+	return tag, byt[start:end], byt[end:], nil
+}
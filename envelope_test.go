@@ -0,0 +1,215 @@
+package hierogolyph
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/cipher/aessiv"
+	hsmgcm "github.com/evalphobia/hierogolyph/hsm/aesgcm"
+	hsmchacha "github.com/evalphobia/hierogolyph/hsm/chacha20poly1305"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelope(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []Envelope{
+		{
+			Version:       EnvelopeVersion1,
+			Cipher:        "aes-256-gcm",
+			Hasher:        "argon2id",
+			HasherParams:  "m=65536,t=1,p=4,l=32",
+			HSM:           "mock-aes-gcm",
+			EncryptionKey: testHierogolyph1.EncryptionKey,
+			CipherText:    []byte("cipher text"),
+		},
+		{
+			Version:       EnvelopeVersion1,
+			EncryptionKey: testHierogolyph2.EncryptionKey,
+			CipherText:    []byte{},
+		},
+		{
+			Version:       EnvelopeVersion1,
+			Cipher:        "aes-256-siv",
+			Deterministic: true,
+			EncryptionKey: testHierogolyph2.EncryptionKey,
+			CipherText:    []byte("cipher text"),
+		},
+		{
+			Version:       EnvelopeVersion1,
+			Padding:       Padding{Mode: PaddingPowerOfTwo, BlockSize: 16, MaxSize: 4096},
+			EncryptionKey: testHierogolyph2.EncryptionKey,
+			CipherText:    []byte("cipher text"),
+		},
+		{
+			Version:       EnvelopeVersion0,
+			EncryptionKey: testHierogolyph3.EncryptionKey,
+			CipherText:    []byte("cipher text"),
+		},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		text, err := tt.Encode()
+		a.NoError(err, target)
+
+		e, err := ParseEnvelope(text)
+		a.NoError(err, target)
+		a.Equal(tt.Version, e.Version, target)
+		a.Equal(tt.Cipher, e.Cipher, target)
+		a.Equal(tt.Hasher, e.Hasher, target)
+		a.Equal(tt.HasherParams, e.HasherParams, target)
+		a.Equal(tt.HSM, e.HSM, target)
+		a.Equal(tt.Deterministic, e.Deterministic, target)
+		a.Equal(tt.Padding, e.Padding, target)
+		a.Equal(tt.EncryptionKey, e.EncryptionKey, target)
+		a.Equal(string(tt.CipherText), string(e.CipherText), target)
+	}
+}
+
+func TestParseEnvelope(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		errMessage string
+		text       string
+	}{
+		{"cipherText is malformed: envelope header is invalid: size=[0]", ""},
+		{"cipherText is malformed: envelope header is invalid: size=[3]", encodeBase64String("XX\x01")},
+		{"cipherText is malformed: envelope version=[2] is not supported", encodeBase64String("HG\x02")},
+		{"cipherText is malformed: envelope field is broken: tag=[1]", encodeBase64String("HG\x01\x01\x05abc")},
+		{"cipherText is malformed: envelope has unknown field: tag=[99]", encodeBase64String("HG\x01\x63\x00")},
+		{"cipherText is malformed: illegal base64 data at input byte 0", "!"},
+		{"cipherText is malformed: cipherText must have one dot `.`: parts=[3]", "a.b.c"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		_, err := ParseEnvelope(tt.text)
+		a.EqualError(err, tt.errMessage, target)
+	}
+}
+
+func TestHierogolyph_EncryptEnvelope(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+
+	cipherText, err := h.Encrypt("plain text")
+	a.NoError(err)
+
+	e, err := ParseEnvelope(cipherText)
+	a.NoError(err)
+	a.Equal(EnvelopeVersion1, e.Version)
+	a.Equal("aes-256-gcm", e.Cipher)
+	a.Equal("argon2id", e.Hasher)
+	a.Equal("m=65536,t=1,p=4,l=32", e.HasherParams)
+	a.Equal("mock-aes-gcm", e.HSM)
+	a.Equal(h.EncryptionKey, e.EncryptionKey)
+	a.False(e.Deterministic)
+}
+
+func TestHierogolyph_EncryptDeterministic(t *testing.T) {
+	a := assert.New(t)
+
+	legacy := testHierogolyph1
+	legacy.Config = testConfig
+	created, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+
+	for _, h := range []Hierogolyph{legacy, created} {
+		target := h.EncryptionKey
+		h.Config.Cipher = aessiv.Cipher{}
+
+		// explicit opt-in is required.
+		_, err = h.Encrypt("plain text")
+		a.EqualError(err, "config is invalid: deterministic cipher requires Config.Deterministic: type=[aessiv.Cipher]", target)
+		a.True(errors.Is(err, ErrInvalidConfig), target)
+
+		h.Config.Deterministic = true
+		cipherText1, err := h.Encrypt("plain text")
+		a.NoError(err, target)
+		cipherText2, err := h.Encrypt("plain text")
+		a.NoError(err, target)
+		a.Equal(cipherText1, cipherText2, target)
+		cipherText3, err := h.Encrypt("other text")
+		a.NoError(err, target)
+		a.NotEqual(cipherText1, cipherText3, target)
+
+		e, err := ParseEnvelope(cipherText1)
+		a.NoError(err, target)
+		a.Equal("aes-256-siv", e.Cipher, target)
+		a.True(e.Deterministic, target)
+
+		// the cipher is resolved by the envelope.
+		h.Config = testConfig
+		plainText, err := h.Decrypt(cipherText1)
+		a.NoError(err, target)
+		a.Equal("plain text", plainText, target)
+
+		// aad
+		h.Config.Cipher = aessiv.Cipher{}
+		h.Config.Deterministic = true
+		aad := AAD("users", "email")
+		cipherText4, err := h.EncryptWithAAD("plain text", aad)
+		a.NoError(err, target)
+		a.NotEqual(cipherText1, cipherText4, target)
+		plainText, err = h.DecryptWithAAD(cipherText4, aad)
+		a.NoError(err, target)
+		a.Equal("plain text", plainText, target)
+	}
+}
+
+func TestHierogolyph_EncryptDeterministicStable(t *testing.T) {
+	a := assert.New(t)
+
+	conf := testConfig
+	conf.Cipher = aessiv.Cipher{}
+	conf.Deterministic = true
+	h, err := CreateHierogolyph("password", conf)
+	a.NoError(err)
+
+	cipherText, err := h.Encrypt("plain text")
+	a.NoError(err)
+	e, err := ParseEnvelope(cipherText)
+	a.NoError(err)
+	a.Empty(e.Hasher)
+	a.Empty(e.HSM)
+	key, err := parseEncryptionKey(e.EncryptionKey)
+	a.NoError(err)
+	a.True(key.isReference())
+
+	// the same cipherText after ChangePassword, RotateHSM and Open.
+	a.NoError(h.ChangePassword("password", "new-p4ssw0rd"))
+	result, err := h.Encrypt("plain text")
+	a.NoError(err)
+	a.Equal(cipherText, result)
+
+	a.NoError(h.RotateHSM(hsmchacha.NewMockHSM([]byte(testGCMKey256))))
+	result, err = h.Encrypt("plain text")
+	a.NoError(err)
+	a.Equal(cipherText, result)
+	result, err = h.RotateHSMCipherText(cipherText, hsmgcm.NewMockHSM([]byte(testGCMKey256)))
+	a.NoError(err)
+	a.Equal(cipherText, result)
+
+	s, err := h.Open()
+	a.NoError(err)
+	result, err = s.Encrypt("plain text")
+	a.NoError(err)
+	a.Equal(cipherText, result)
+
+	plainText, err := h.Decrypt(cipherText)
+	a.NoError(err)
+	a.Equal("plain text", plainText)
+
+	// another key cannot decrypt it.
+	other, err := CreateHierogolyph("password", conf)
+	a.NoError(err)
+	_, err = other.Decrypt(cipherText)
+	a.True(errors.Is(err, ErrWrongKey), err)
+}
@@ -0,0 +1,65 @@
+package hierogolyph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Errors returned by Hierogolyph, which can be checked by errors.Is.
+// Error messages never contain plainText, password or key.
+var (
+	// ErrMalformedCipherText is returned when cipherText or its envelope cannot be parsed.
+	ErrMalformedCipherText = errors.New("cipherText is malformed")
+	// ErrIntegrity is returned when decrypted data is not verified by HMAC or stream is truncated.
+	ErrIntegrity = errors.New("integrity check failed")
+	// ErrWrongKey is returned when cipherText cannot be decrypted.
+	// The password, salt, EncryptionKey or aad is wrong, or cipherText is modified.
+	ErrWrongKey = errors.New("key is wrong or cipherText is modified")
+	// ErrHSM is returned when HSM fails.
+	ErrHSM = errors.New("hsm error")
+	// ErrHasher is returned when Hasher fails.
+	ErrHasher = errors.New("hasher error")
+	// ErrInvalidConfig is returned when Config or arguments are invalid. (e.g. empty aad)
+	ErrInvalidConfig = errors.New("config is invalid")
+)
+
+// Error is an error with its kind and cause.
+// errors.Is(err, Kind) is true, and errors.As can be used to get the Error.
+type Error struct {
+	Kind error // one of ErrMalformedCipherText, ErrIntegrity, ErrWrongKey, ErrHSM, ErrHasher and ErrInvalidConfig.
+	Err  error // cause of the error, can be nil.
+}
+
+// Error returns error message.
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Kind.Error(), e.Err.Error())
+}
+
+// Unwrap returns the cause.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of the error.
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// newError returns Error of the kind.
+// The err is returned as is when it's already Error or context error.
+func newError(kind, err error) error {
+	var e *Error
+	switch {
+	case err == nil:
+		return &Error{Kind: kind}
+	case errors.As(err, &e),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
@@ -0,0 +1,100 @@
+package hierogolyph
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/hasher/scrypt"
+	hsmgcm "github.com/evalphobia/hierogolyph/hsm/aesgcm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	a := assert.New(t)
+
+	cause := errors.New("cause")
+	tests := []struct {
+		kind       error
+		err        error
+		errMessage string
+	}{
+		{ErrMalformedCipherText, nil, "cipherText is malformed"},
+		{ErrIntegrity, cause, "integrity check failed: cause"},
+		{ErrWrongKey, cause, "key is wrong or cipherText is modified: cause"},
+		{ErrHSM, cause, "hsm error: cause"},
+		{ErrHasher, cause, "hasher error: cause"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		err := newError(tt.kind, tt.err)
+		a.EqualError(err, tt.errMessage, target)
+		a.True(errors.Is(err, tt.kind), target)
+		a.Equal(tt.err != nil, errors.Is(err, cause), target)
+
+		var e *Error
+		a.True(errors.As(err, &e), target)
+		a.Equal(tt.kind, e.Kind, target)
+
+		// already wrapped error is not wrapped again.
+		a.Equal(err, newError(ErrHSM, fmt.Errorf("wrap: %w", err)).(interface{ Unwrap() error }).Unwrap(), target)
+	}
+}
+
+func TestHierogolyph_DecryptError(t *testing.T) {
+	a := assert.New(t)
+	const secret = "secret plain text"
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	cipherText, err := h.Encrypt(secret)
+	a.NoError(err)
+
+	wrongPassword := h
+	wrongPassword.Password = "bad-p4ssw0rd"
+	wrongHMAC := h
+	wrongHMAC.Config.HMACKey = "wrong"
+	wrongHSM := h
+	wrongHSM.Config.HSM = hsmgcm.NewMockHSM(nil)
+	wrongHasher := h
+	wrongHasher.Config.Hasher = scrypt.SCrypt{Cost: 3}
+	e, err := ParseEnvelope(cipherText)
+	a.NoError(err)
+	e.CipherText[len(e.CipherText)-5] ^= 0x01
+	tampered, err := e.Encode()
+	a.NoError(err)
+	e, err = ParseEnvelope(cipherText)
+	a.NoError(err)
+	e.Hasher, e.HasherParams = "argon2id", "m=4294967295,t=4294967295,p=4,l=32"
+	oversized, err := e.Encode()
+	a.NoError(err)
+
+	tests := []struct {
+		name       string
+		h          Hierogolyph
+		cipherText string
+		kind       error
+	}{
+		{"malformed", h, "!", ErrMalformedCipherText},
+		{"wrong password", wrongPassword, cipherText, ErrWrongKey},
+		{"tampered", h, tampered, ErrWrongKey},
+		{"wrong hmac key", wrongHMAC, cipherText, ErrIntegrity},
+		{"hsm error", wrongHSM, cipherText, ErrHSM},
+		{"oversized hasher params", h, oversized, ErrHasher},
+	}
+
+	for _, tt := range tests {
+		_, err := tt.h.Decrypt(tt.cipherText)
+		a.True(errors.Is(err, tt.kind), tt.name, err)
+		a.NotContains(err.Error(), secret, tt.name)
+		a.NotContains(err.Error(), tt.h.Password, tt.name)
+	}
+
+	// hasher error
+	_, err = wrongHasher.Unlock()
+	a.True(errors.Is(err, ErrHasher), err)
+	_, err = Hierogolyph{Config: Config{}}.Unlock()
+	a.True(errors.Is(err, ErrHasher), err)
+}
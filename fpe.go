@@ -0,0 +1,99 @@
+package hierogolyph
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/evalphobia/hierogolyph/crypto/fpe"
+	"github.com/evalphobia/hierogolyph/hsm"
+)
+
+// modes of format-preserving encryption.
+const (
+	FPEModeFF1  = "ff1"
+	FPEModeFF31 = "ff3-1"
+)
+
+const (
+	fpeKeyInfo = "hierogolyph fpe"
+	fpeKeySize = 32
+)
+
+// NewFPE returns format-preserving cipher, whose key is derived from the session key, the mode and the name.
+// Use different names for different fields (e.g. `ssn`, `phone`), and fpe.EncryptFormatted to keep separators.
+func (s *Session) NewFPE(mode, name, alphabet string) (fpe.Cipher, error) {
+	if name == "" {
+		return nil, errors.New("fpe name must not be empty")
+	}
+	secret, err := s.secret()
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+
+	key := make([]byte, fpeKeySize)
+	defer zeroBytes(key)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, AAD(fpeKeyInfo, mode, name)), key); err != nil {
+		return nil, err
+	}
+	return newFPE(mode, key, alphabet)
+}
+
+// NewFPE unlocks Hierogolyph and returns format-preserving cipher, see Session.NewFPE.
+func (h Hierogolyph) NewFPE(mode, name, alphabet string) (fpe.Cipher, error) {
+	s, err := h.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.NewFPE(mode, name, alphabet)
+}
+
+// GenerateFPEKey generates a random key of format-preserving encryption, and returns it wrapped by HSM.
+// The key is not bound to the user, so it's used for values shared by the organisation. (e.g. tokenized card numbers)
+func GenerateFPEKey(h hsm.HSM) (wrappedKey string, err error) {
+	key, err := getRandomBytes(fpeKeySize)
+	if err != nil {
+		return "", err
+	}
+	defer zeroBytes(key)
+
+	encrypted, err := hsm.ToByteHSM(h).EncryptBytes(key)
+	if err != nil {
+		return "", newError(ErrHSM, err)
+	}
+	return encodeBase64(encrypted), nil
+}
+
+// OpenFPE unwraps the key created by GenerateFPEKey, and returns format-preserving cipher.
+func OpenFPE(ctx context.Context, h hsm.HSM, wrappedKey, mode, alphabet string) (fpe.Cipher, error) {
+	encrypted, err := decodeBase64(wrappedKey)
+	if err != nil {
+		return nil, newError(ErrWrongKey, err)
+	}
+	key, err := hsm.ToContextHSM(h).DecryptContext(ctx, []byte(encrypted))
+	switch {
+	case errors.Is(err, hsm.ErrInvalidCipherText):
+		return nil, newError(ErrWrongKey, err)
+	case err != nil:
+		return nil, newError(ErrHSM, err)
+	}
+	defer zeroBytes(key)
+	return newFPE(mode, key, alphabet)
+}
+
+// newFPE creates format-preserving cipher of the mode.
+func newFPE(mode string, key []byte, alphabet string) (fpe.Cipher, error) {
+	switch mode {
+	case FPEModeFF1:
+		return fpe.NewFF1(key, alphabet)
+	case FPEModeFF31:
+		return fpe.NewFF3(key, alphabet)
+	}
+	return nil, fmt.Errorf("fpe mode is unknown: mode=[%s]", mode)
+}
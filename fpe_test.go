@@ -0,0 +1,89 @@
+package hierogolyph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/crypto/fpe"
+	hsmgcm "github.com/evalphobia/hierogolyph/hsm/aesgcm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_NewFPE(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	s, err := h.Open()
+	a.NoError(err)
+	tweak := make([]byte, fpe.FF3TweakSize)
+
+	tests := []struct {
+		mode string
+	}{
+		{FPEModeFF1},
+		{FPEModeFF31},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		c, err := s.NewFPE(tt.mode, "ssn", fpe.Digits)
+		a.NoError(err, target)
+		result, err := fpe.EncryptFormatted(c, "123-45-6789", tweak)
+		a.NoError(err, target)
+		a.Regexp(`^\d{3}-\d{2}-\d{4}$`, result, target)
+		a.NotEqual("123-45-6789", result, target)
+
+		// the same key is derived from Hierogolyph.
+		c, err = h.NewFPE(tt.mode, "ssn", fpe.Digits)
+		a.NoError(err, target)
+		plainText, err := fpe.DecryptFormatted(c, result, tweak)
+		a.NoError(err, target)
+		a.Equal("123-45-6789", plainText, target)
+
+		// the key is different by the name.
+		c, err = s.NewFPE(tt.mode, "phone", fpe.Digits)
+		a.NoError(err, target)
+		other, err := fpe.EncryptFormatted(c, "123-45-6789", tweak)
+		a.NoError(err, target)
+		a.NotEqual(result, other, target)
+	}
+
+	_, err = s.NewFPE("ff3", "ssn", fpe.Digits)
+	a.EqualError(err, "fpe mode is unknown: mode=[ff3]")
+	_, err = s.NewFPE(FPEModeFF1, "", fpe.Digits)
+	a.EqualError(err, "fpe name must not be empty")
+	a.NoError(s.Close())
+	_, err = s.NewFPE(FPEModeFF1, "ssn", fpe.Digits)
+	a.EqualError(err, "session is closed")
+}
+
+func TestOpenFPE(t *testing.T) {
+	a := assert.New(t)
+
+	wrappedKey, err := GenerateFPEKey(testConfig.HSM)
+	a.NoError(err)
+
+	c, err := OpenFPE(context.Background(), testConfig.HSM, wrappedKey, FPEModeFF1, fpe.Alphanumeric)
+	a.NoError(err)
+	result, err := c.Encrypt("4111111111111111", nil)
+	a.NoError(err)
+	a.Len(result, 16)
+
+	c, err = OpenFPE(context.Background(), testConfig.HSM, wrappedKey, FPEModeFF1, fpe.Alphanumeric)
+	a.NoError(err)
+	plainText, err := c.Decrypt(result, nil)
+	a.NoError(err)
+	a.Equal("4111111111111111", plainText)
+
+	// another HSM key
+	otherHSM := hsmgcm.NewMockHSM([]byte("12345678901234567890123456789012"))
+	_, err = OpenFPE(context.Background(), otherHSM, wrappedKey, FPEModeFF1, fpe.Alphanumeric)
+	a.True(errors.Is(err, ErrWrongKey))
+	_, err = OpenFPE(context.Background(), testConfig.HSM, "!", FPEModeFF1, fpe.Alphanumeric)
+	a.True(errors.Is(err, ErrWrongKey))
+}
@@ -0,0 +1,17 @@
+module github.com/evalphobia/hierogolyph
+
+go 1.21
+
+require (
+	github.com/aws/aws-sdk-go v1.55.8
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/crypto v0.33.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
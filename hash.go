@@ -18,3 +18,10 @@ func HashHMAC(plainText, key string) string {
 	_, _ = mac.Write([]byte(plainText))
 	return hex.EncodeToString(mac.Sum(nil))
 }
+
+// hashHMACBytes returns a raw HMAC of the data using the given key.
+func hashHMACBytes(data []byte, key string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	_, _ = mac.Write(data)
+	return mac.Sum(nil)
+}
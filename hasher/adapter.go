@@ -0,0 +1,85 @@
+package hasher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrInvalidOutLen is returned by Deriver when outLen is not positive.
+var ErrInvalidOutLen = errors.New("hasher: outLen must be positive")
+
+var expandInfo = []byte("hierogolyph hasher")
+
+// ToByteHasher returns ByteHasher from Hasher.
+// When h does not implement ByteHasher, hex encoded digest of h is decoded.
+func ToByteHasher(h Hasher) ByteHasher {
+	if v, ok := h.(ByteHasher); ok {
+		return v
+	}
+	return byteHasher{h}
+}
+
+// byteHasher is adapter of Hasher for ByteHasher.
+type byteHasher struct {
+	Hasher
+}
+
+// HashBytes returns raw digest.
+// It returns nil when the digest is not hex encoded.
+func (h byteHasher) HashBytes(password, salt []byte) []byte {
+	digest, err := hex.DecodeString(h.Hash(string(password), string(salt)))
+	if err != nil {
+		return nil
+	}
+	return digest
+}
+
+// ToDeriver returns Deriver from Hasher.
+// When h does not implement Deriver, the raw digest of h is expanded to outLen, see Expand.
+func ToDeriver(h Hasher) Deriver {
+	if v, ok := h.(Deriver); ok {
+		return v
+	}
+	return deriver{h}
+}
+
+// deriver is adapter of Hasher for Deriver.
+type deriver struct {
+	Hasher
+}
+
+// Derive returns raw key of outLen bytes.
+func (h deriver) Derive(password, salt []byte, outLen int) ([]byte, error) {
+	if v, ok := h.Hasher.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return Expand(ToByteHasher(h.Hasher).HashBytes(password, salt), outLen)
+}
+
+// Expand returns key of outLen bytes from the digest.
+// The digest is truncated when it's long enough, otherwise it's expanded by HKDF-SHA256.
+func Expand(digest []byte, outLen int) ([]byte, error) {
+	switch {
+	case outLen <= 0:
+		return nil, ErrInvalidOutLen
+	case len(digest) == 0:
+		return nil, errors.New("hasher: digest is empty")
+	case outLen <= len(digest):
+		return append([]byte{}, digest[:outLen]...), nil
+	case outLen > 255*sha256.Size:
+		return nil, fmt.Errorf("hasher: outLen is too large: outLen=[%d]", outLen)
+	}
+
+	key := make([]byte, outLen)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, digest, expandInfo), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,119 @@
+package hasher_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/hasher"
+	"github.com/evalphobia/hierogolyph/hasher/insecure/sha2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stringHasher implements only hasher.Hasher.
+type stringHasher struct {
+	digest string
+}
+
+func (s stringHasher) Hash(password, salt string) string {
+	if s.digest != "" {
+		return s.digest
+	}
+	return sha2.Sha256{}.Hash(password, salt)
+}
+
+func TestToByteHasher(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		password string
+		salt     string
+	}{
+		{"", ""},
+		{"password", "salt"},
+		{"あいうえお", "salt"},
+	}
+
+	a.Equal(sha2.Sha256{}, hasher.ToByteHasher(sha2.Sha256{}))
+
+	bh := hasher.ToByteHasher(stringHasher{})
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		expected := sha2.Sha256{}.Hash(tt.password, tt.salt)
+		result := bh.HashBytes([]byte(tt.password), []byte(tt.salt))
+		a.Equal(expected, hex.EncodeToString(result), target)
+	}
+
+	// not hex encoded
+	bh = hasher.ToByteHasher(stringHasher{digest: "not hex"})
+	a.Nil(bh.HashBytes([]byte("password"), []byte("salt")))
+}
+
+func TestToDeriver(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(sha2.Sha256{}, hasher.ToDeriver(sha2.Sha256{}))
+
+	d := hasher.ToDeriver(stringHasher{})
+	digest := sha2.Sha256{}.HashBytes([]byte("password"), []byte("salt"))
+
+	tests := []struct {
+		outLen    int
+		truncated bool
+	}{
+		{1, true},
+		{32, true},
+		{64, false},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		key, err := d.Derive([]byte("password"), []byte("salt"), tt.outLen)
+		a.NoError(err, target)
+		a.Len(key, tt.outLen, target)
+		if tt.truncated {
+			a.Equal(digest[:tt.outLen], key, target)
+			continue
+		}
+		expanded, err := hasher.Expand(digest, tt.outLen)
+		a.NoError(err, target)
+		a.Equal(expanded, key, target)
+	}
+
+	_, err := d.Derive([]byte("password"), []byte("salt"), 0)
+	a.Equal(hasher.ErrInvalidOutLen, err)
+	_, err = hasher.ToDeriver(stringHasher{digest: "not hex"}).Derive([]byte("password"), []byte("salt"), 32)
+	a.EqualError(err, "hasher: digest is empty")
+}
+
+func TestExpand(t *testing.T) {
+	a := assert.New(t)
+	digest := []byte("12345678901234567890123456789012")
+
+	key, err := hasher.Expand(digest, 16)
+	a.NoError(err)
+	a.Equal(digest[:16], key)
+
+	key, err = hasher.Expand(digest, 64)
+	a.NoError(err)
+	a.Equal("150765cbc3b171c33b84b462053777e06319ec3a2444ffac7de007befcdeaa4556cc0e34c9781a2b929634b4a694593caf461204080d7e57f05f19e83e23d557", hex.EncodeToString(key))
+
+	tests := []struct {
+		digest   []byte
+		outLen   int
+		expected string
+	}{
+		{digest, 0, "hasher: outLen must be positive"},
+		{digest, -1, "hasher: outLen must be positive"},
+		{nil, 32, "hasher: digest is empty"},
+		{digest, 255*32 + 1, "hasher: outLen is too large: outLen=[8161]"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		_, err := hasher.Expand(tt.digest, tt.outLen)
+		a.EqualError(err, tt.expected, target)
+	}
+}
@@ -2,16 +2,27 @@ package argon2
 
 import (
 	"encoding/hex"
+	"fmt"
+	"math"
 
 	"golang.org/x/crypto/argon2"
+
+	"github.com/evalphobia/hierogolyph/hasher"
 )
 
 const (
+	algorithmName = "argon2id"
+
 	// see: https://godoc.org/golang.org/x/crypto/argon2
 	defaultArgon2Time      = 1
 	defaultArgon2Memory    = 64 * 1024
 	defaultArgon2Threads   = 4
 	defaultArgon2KeyLength = 32
+
+	// maximum cost parameters restored by WithParams.
+	maxArgon2Time      = 16
+	maxArgon2Memory    = 1024 * 1024
+	maxArgon2KeyLength = 1024
 )
 
 // Argon2 is struct to create hash using Argon2id.
@@ -24,14 +35,84 @@ type Argon2 struct {
 
 // Hash creates hased text from password and salt using Argon2id.
 func (a Argon2) Hash(password, salt string) string {
-	return hex.EncodeToString(argon2.IDKey(
-		[]byte(password),
-		[]byte(salt),
+	return hex.EncodeToString(a.HashBytes([]byte(password), []byte(salt)))
+}
+
+// HashBytes creates raw hash from password and salt using Argon2id.
+func (a Argon2) HashBytes(password, salt []byte) []byte {
+	return argon2.IDKey(
+		password,
+		salt,
 		a.getTime(),
 		a.getMemory(),
 		a.getThreads(),
 		a.getKeyLength(),
-	))
+	)
+}
+
+// Derive creates raw key of outLen bytes from password and salt using Argon2id.
+// KeyLength is not used.
+func (a Argon2) Derive(password, salt []byte, outLen int) ([]byte, error) {
+	if outLen <= 0 || uint64(outLen) > math.MaxUint32 {
+		return nil, hasher.ErrInvalidOutLen
+	}
+	return argon2.IDKey(
+		password,
+		salt,
+		a.getTime(),
+		a.getMemory(),
+		a.getThreads(),
+		uint32(outLen),
+	), nil
+}
+
+// Size returns byte size of the digest.
+func (a Argon2) Size() int {
+	return int(a.getKeyLength())
+}
+
+// Algorithm returns algorithm name.
+func (Argon2) Algorithm() string {
+	return algorithmName
+}
+
+// Params returns cost parameters.
+func (a Argon2) Params() string {
+	return fmt.Sprintf("m=%d,t=%d,p=%d,l=%d", a.getMemory(), a.getTime(), a.getThreads(), a.getKeyLength())
+}
+
+// WithParams returns Argon2 which has given cost parameters.
+// Each parameter must not exceed the larger of the receiver's and the default maximum,
+// to prevent resource exhaustion by the parameters recorded in untrusted ciphertext.
+func (a Argon2) WithParams(params string) (hasher.Hasher, error) {
+	p, err := hasher.ParseParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	memory, err := p.Get("m", hasher.Limit(uint64(a.getMemory()), maxArgon2Memory))
+	if err != nil {
+		return nil, err
+	}
+	time, err := p.Get("t", hasher.Limit(uint64(a.getTime()), maxArgon2Time))
+	if err != nil {
+		return nil, err
+	}
+	threads, err := p.Get("p", math.MaxUint8)
+	if err != nil {
+		return nil, err
+	}
+	keyLength, err := p.Get("l", hasher.Limit(uint64(a.getKeyLength()), maxArgon2KeyLength))
+	if err != nil {
+		return nil, err
+	}
+
+	return Argon2{
+		Time:      uint32(time),
+		Memory:    uint32(memory),
+		Threads:   uint8(threads),
+		KeyLength: uint32(keyLength),
+	}, nil
 }
 
 func (a Argon2) getTime() uint32 {
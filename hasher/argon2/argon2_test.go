@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/evalphobia/hierogolyph/hasher"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -62,3 +64,59 @@ func TestArgon2_Hash(t *testing.T) {
 		a.NotEqual(tt.expected, result, target, "double key length")
 	}
 }
+
+func TestArgon2_WithParams(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		hasher   Argon2
+		expected string
+	}{
+		{Argon2{}, "m=65536,t=1,p=4,l=32"},
+		{Argon2{Time: 2, Memory: 1024, Threads: 2, KeyLength: 64}, "m=1024,t=2,p=2,l=64"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, tt.hasher.Params(), target)
+
+		restored, err := Argon2{}.WithParams(tt.expected)
+		a.NoError(err, target)
+		a.Equal(tt.hasher.Hash("password", "salt"), restored.Hash("password", "salt"), target)
+	}
+
+	_, err := Argon2{}.WithParams("p=256")
+	a.Error(err)
+
+	// cost parameters exceeding the maximum are rejected, unless the receiver has higher cost.
+	_, err = Argon2{}.WithParams("m=4294967295")
+	a.Error(err)
+	_, err = Argon2{Memory: 2 * 1024 * 1024}.WithParams("m=2097152")
+	a.NoError(err)
+}
+
+func TestArgon2_Derive(t *testing.T) {
+	a := assert.New(t)
+	h := Argon2{}
+
+	tests := []struct {
+		outLen int
+	}{
+		{16},
+		{32},
+		{64},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		key, err := h.Derive([]byte("password"), []byte("salt"), tt.outLen)
+		a.NoError(err, target)
+		a.Len(key, tt.outLen, target)
+		if tt.outLen == h.Size() {
+			a.Equal(h.HashBytes([]byte("password"), []byte("salt")), key, target)
+		}
+	}
+
+	_, err := h.Derive([]byte("password"), []byte("salt"), 0)
+	a.Equal(hasher.ErrInvalidOutLen, err)
+}
@@ -3,16 +3,27 @@ package balloon
 import (
 	"crypto/sha512"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"hash"
 
 	"github.com/nogoegst/balloon"
+
+	"github.com/evalphobia/hierogolyph/hasher"
 )
 
 const (
+	algorithmName = "balloon"
+
 	// see: https://godoc.org/github.com/nogoegst/balloon
 	defaultSpaceCost   = 16
 	defaultTimeCost    = 16
 	defaultParallelism = 1
+
+	// maximum cost parameters restored by WithParams.
+	maxSpaceCost   = 1 << 20
+	maxTimeCost    = 64
+	maxParallelism = 16
 )
 
 var (
@@ -29,14 +40,84 @@ type Balloon struct {
 
 // Hash creates hased text from password.
 func (b Balloon) Hash(password, salt string) string {
-	return hex.EncodeToString(balloon.BalloonM(
+	return hex.EncodeToString(b.HashBytes([]byte(password), []byte(salt)))
+}
+
+// HashBytes creates raw hash from password.
+func (b Balloon) HashBytes(password, salt []byte) []byte {
+	return balloon.BalloonM(
 		b.getHashFn(),
-		[]byte(password),
-		[]byte(salt),
+		password,
+		salt,
 		b.getSpaceCost(),
 		b.getTimeCost(),
 		b.getParallelism(),
-	))
+	)
+}
+
+// Derive creates raw key of outLen bytes from password and salt.
+// The digest is expanded when outLen is larger than the size of HashFn, see hasher.Expand.
+func (b Balloon) Derive(password, salt []byte, outLen int) ([]byte, error) {
+	return hasher.Expand(b.HashBytes(password, salt), outLen)
+}
+
+// Size returns byte size of the digest, which is the size of HashFn.
+func (b Balloon) Size() int {
+	return b.getHashFn()().Size()
+}
+
+// Algorithm returns algorithm name with the hash function. (e.g. `balloon-sha512`)
+// The name does not contain hash function when HashFn is unknown, which is rejected by Validate.
+func (b Balloon) Algorithm() string {
+	name := hasher.HashName(b.getHashFn())
+	if name == "" {
+		return algorithmName
+	}
+	return algorithmName + "-" + name
+}
+
+// Validate checks HashFn is identified by hasher.HashName, so the algorithm name can restore it.
+func (b Balloon) Validate() error {
+	if hasher.HashName(b.getHashFn()) == "" {
+		return errors.New("balloon: hash function is unknown")
+	}
+	return nil
+}
+
+// Params returns cost parameters.
+func (b Balloon) Params() string {
+	return fmt.Sprintf("s=%d,t=%d,p=%d", b.getSpaceCost(), b.getTimeCost(), b.getParallelism())
+}
+
+// WithParams returns Balloon which has given cost parameters.
+// HashFn is inherited from the receiver.
+// Each parameter must not exceed the larger of the receiver's and the default maximum,
+// to prevent resource exhaustion by the parameters recorded in untrusted ciphertext.
+func (b Balloon) WithParams(params string) (hasher.Hasher, error) {
+	p, err := hasher.ParseParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	spaceCost, err := p.Get("s", hasher.Limit(b.getSpaceCost(), maxSpaceCost))
+	if err != nil {
+		return nil, err
+	}
+	timeCost, err := p.Get("t", hasher.Limit(b.getTimeCost(), maxTimeCost))
+	if err != nil {
+		return nil, err
+	}
+	parallelism, err := p.Get("p", hasher.Limit(b.getParallelism(), maxParallelism))
+	if err != nil {
+		return nil, err
+	}
+
+	return Balloon{
+		HashFn:      b.HashFn,
+		SpaceCost:   spaceCost,
+		TimeCost:    timeCost,
+		Parallelism: parallelism,
+	}, nil
 }
 
 func (b Balloon) getHashFn() func() hash.Hash {
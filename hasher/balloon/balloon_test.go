@@ -1,9 +1,14 @@
 package balloon
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"hash"
+	"hash/fnv"
 	"testing"
 
+	"github.com/evalphobia/hierogolyph/hasher"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,3 +43,79 @@ func TestBalloon_Hash(t *testing.T) {
 		a.NotEqual(tt.expected, result, target, "using invalid key")
 	}
 }
+
+func TestBalloon_Algorithm(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		hasher   Balloon
+		expected string
+		valid    bool
+	}{
+		{Balloon{}, "balloon-sha512", true},
+		{Balloon{HashFn: sha256.New}, "balloon-sha256", true},
+		{Balloon{HashFn: func() hash.Hash { return fnv.New128() }}, "balloon", false},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, tt.hasher.Algorithm(), target)
+		a.Equal(tt.valid, tt.hasher.Validate() == nil, target)
+	}
+}
+
+func TestBalloon_WithParams(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		hasher   Balloon
+		expected string
+	}{
+		{Balloon{}, "s=16,t=16,p=1"},
+		{Balloon{SpaceCost: 8, TimeCost: 4, Parallelism: 2}, "s=8,t=4,p=2"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, tt.hasher.Params(), target)
+
+		restored, err := Balloon{}.WithParams(tt.expected)
+		a.NoError(err, target)
+		a.Equal(tt.hasher.Hash("password", "salt"), restored.Hash("password", "salt"), target)
+	}
+
+	_, err := Balloon{}.WithParams("p=256")
+	a.Error(err)
+
+	// cost parameters exceeding the maximum are rejected, unless the receiver has higher cost.
+	_, err = Balloon{}.WithParams("s=4294967295")
+	a.Error(err)
+	_, err = Balloon{SpaceCost: 1 << 21}.WithParams("s=2097152")
+	a.NoError(err)
+}
+
+func TestBalloon_Derive(t *testing.T) {
+	a := assert.New(t)
+	h := Balloon{}
+	digest := h.HashBytes([]byte("password"), []byte("salt"))
+
+	tests := []struct {
+		outLen int
+	}{
+		{16},
+		{64},
+		{128},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		key, err := h.Derive([]byte("password"), []byte("salt"), tt.outLen)
+		a.NoError(err, target)
+		expected, err := hasher.Expand(digest, tt.outLen)
+		a.NoError(err, target)
+		a.Equal(expected, key, target)
+	}
+
+	_, err := h.Derive([]byte("password"), []byte("salt"), 0)
+	a.Equal(hasher.ErrInvalidOutLen, err)
+}
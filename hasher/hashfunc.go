@@ -0,0 +1,52 @@
+package hasher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// hashFuncs are hash functions which can be identified by HashName.
+var hashFuncs = []struct {
+	name string
+	fn   func() hash.Hash
+}{
+	{"sha224", sha256.New224},
+	{"sha256", sha256.New},
+	{"sha384", sha512.New384},
+	{"sha512", sha512.New},
+	{"sha512-224", sha512.New512_224},
+	{"sha512-256", sha512.New512_256},
+	{"sha3-224", sha3.New224},
+	{"sha3-256", sha3.New256},
+	{"sha3-384", sha3.New384},
+	{"sha3-512", sha3.New512},
+}
+
+// hashNameProbe is hashed to identify the hash function.
+var hashNameProbe = []byte("hierogolyph hash function")
+
+// HashName returns the name of the hash function (e.g. `sha512`), which is recorded in the algorithm name.
+// The hash function is identified by its digest, and empty string is returned when it's unknown.
+func HashName(fn func() hash.Hash) string {
+	if fn == nil {
+		return ""
+	}
+	sum := probeHash(fn)
+	for _, f := range hashFuncs {
+		if bytes.Equal(sum, probeHash(f.fn)) {
+			return f.name
+		}
+	}
+	return ""
+}
+
+// probeHash returns digest of hashNameProbe.
+func probeHash(fn func() hash.Hash) []byte {
+	h := fn()
+	_, _ = h.Write(hashNameProbe)
+	return h.Sum(nil)
+}
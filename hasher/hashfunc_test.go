@@ -0,0 +1,37 @@
+package hasher
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashName(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		fn       func() hash.Hash
+		expected string
+	}{
+		{sha256.New, "sha256"},
+		{sha256.New224, "sha224"},
+		{sha512.New, "sha512"},
+		{sha512.New384, "sha384"},
+		{sha3.New256, "sha3-256"},
+		{func() hash.Hash { return sha512.New() }, "sha512"},
+		{func() hash.Hash { return fnv.New64() }, ""},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, HashName(tt.fn), target)
+	}
+}
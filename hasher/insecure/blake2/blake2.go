@@ -5,22 +5,74 @@ import (
 
 	"golang.org/x/crypto/blake2b"
 	"golang.org/x/crypto/blake2s"
+
+	"github.com/evalphobia/hierogolyph/hasher"
 )
 
 // Blake2b is struct to create hash.
 type Blake2b struct{}
 
+// Algorithm returns algorithm name.
+func (Blake2b) Algorithm() string {
+	return "blake2b-256"
+}
+
+// Params returns empty string, there is no cost parameter.
+func (Blake2b) Params() string {
+	return ""
+}
+
+// Derive creates raw key of outLen bytes from password, see hasher.Expand.
+func (h Blake2b) Derive(password, salt []byte, outLen int) ([]byte, error) {
+	return hasher.Expand(h.HashBytes(password, salt), outLen)
+}
+
+// Size returns byte size of the digest.
+func (Blake2b) Size() int {
+	return blake2b.Size256
+}
+
 // Hash creates hased text from password.
-func (Blake2b) Hash(password, salt string) string {
-	b := blake2b.Sum256([]byte(password + salt))
-	return hex.EncodeToString(b[:])
+func (h Blake2b) Hash(password, salt string) string {
+	return hex.EncodeToString(h.HashBytes([]byte(password), []byte(salt)))
+}
+
+// HashBytes creates raw hash from password.
+func (Blake2b) HashBytes(password, salt []byte) []byte {
+	b := blake2b.Sum256(append(append([]byte{}, password...), salt...))
+	return b[:]
 }
 
 // Blake2s is struct to create hash.
 type Blake2s struct{}
 
+// Algorithm returns algorithm name.
+func (Blake2s) Algorithm() string {
+	return "blake2s-256"
+}
+
+// Params returns empty string, there is no cost parameter.
+func (Blake2s) Params() string {
+	return ""
+}
+
+// Derive creates raw key of outLen bytes from password, see hasher.Expand.
+func (h Blake2s) Derive(password, salt []byte, outLen int) ([]byte, error) {
+	return hasher.Expand(h.HashBytes(password, salt), outLen)
+}
+
+// Size returns byte size of the digest.
+func (Blake2s) Size() int {
+	return blake2s.Size
+}
+
 // Hash creates hased text from password.
-func (Blake2s) Hash(password, salt string) string {
-	b := blake2s.Sum256([]byte(password + salt))
-	return hex.EncodeToString(b[:])
+func (h Blake2s) Hash(password, salt string) string {
+	return hex.EncodeToString(h.HashBytes([]byte(password), []byte(salt)))
+}
+
+// HashBytes creates raw hash from password.
+func (Blake2s) HashBytes(password, salt []byte) []byte {
+	b := blake2s.Sum256(append(append([]byte{}, password...), salt...))
+	return b[:]
 }
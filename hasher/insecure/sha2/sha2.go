@@ -5,22 +5,74 @@ import (
 
 	"crypto/sha256"
 	"crypto/sha512"
+
+	"github.com/evalphobia/hierogolyph/hasher"
 )
 
 // Sha512 is struct to create hash.
 type Sha512 struct{}
 
+// Algorithm returns algorithm name.
+func (Sha512) Algorithm() string {
+	return "sha512-256"
+}
+
+// Params returns empty string, there is no cost parameter.
+func (Sha512) Params() string {
+	return ""
+}
+
+// Derive creates raw key of outLen bytes from password, see hasher.Expand.
+func (h Sha512) Derive(password, salt []byte, outLen int) ([]byte, error) {
+	return hasher.Expand(h.HashBytes(password, salt), outLen)
+}
+
+// Size returns byte size of the digest.
+func (Sha512) Size() int {
+	return sha512.Size256
+}
+
 // Hash creates hased text from password.
-func (Sha512) Hash(password, salt string) string {
-	b := sha512.Sum512_256([]byte(password + salt))
-	return hex.EncodeToString(b[:])
+func (h Sha512) Hash(password, salt string) string {
+	return hex.EncodeToString(h.HashBytes([]byte(password), []byte(salt)))
+}
+
+// HashBytes creates raw hash from password.
+func (Sha512) HashBytes(password, salt []byte) []byte {
+	b := sha512.Sum512_256(append(append([]byte{}, password...), salt...))
+	return b[:]
 }
 
 // Sha256 is struct to create hash.
 type Sha256 struct{}
 
+// Algorithm returns algorithm name.
+func (Sha256) Algorithm() string {
+	return "sha256"
+}
+
+// Params returns empty string, there is no cost parameter.
+func (Sha256) Params() string {
+	return ""
+}
+
+// Derive creates raw key of outLen bytes from password, see hasher.Expand.
+func (h Sha256) Derive(password, salt []byte, outLen int) ([]byte, error) {
+	return hasher.Expand(h.HashBytes(password, salt), outLen)
+}
+
+// Size returns byte size of the digest.
+func (Sha256) Size() int {
+	return sha256.Size
+}
+
 // Hash creates hased text from password.
-func (Sha256) Hash(password, salt string) string {
-	b := sha256.Sum256([]byte(password + salt))
-	return hex.EncodeToString(b[:])
+func (h Sha256) Hash(password, salt string) string {
+	return hex.EncodeToString(h.HashBytes([]byte(password), []byte(salt)))
+}
+
+// HashBytes creates raw hash from password.
+func (Sha256) HashBytes(password, salt []byte) []byte {
+	b := sha256.Sum256(append(append([]byte{}, password...), salt...))
+	return b[:]
 }
@@ -4,13 +4,40 @@ import (
 	"encoding/hex"
 
 	"golang.org/x/crypto/sha3"
+
+	"github.com/evalphobia/hierogolyph/hasher"
 )
 
 // Sha256 is struct to create hash.
 type Sha256 struct{}
 
+// Algorithm returns algorithm name.
+func (Sha256) Algorithm() string {
+	return "sha3-256"
+}
+
+// Params returns empty string, there is no cost parameter.
+func (Sha256) Params() string {
+	return ""
+}
+
+// Derive creates raw key of outLen bytes from password, see hasher.Expand.
+func (h Sha256) Derive(password, salt []byte, outLen int) ([]byte, error) {
+	return hasher.Expand(h.HashBytes(password, salt), outLen)
+}
+
+// Size returns byte size of the digest.
+func (Sha256) Size() int {
+	return 32
+}
+
 // Hash creates hased text from password.
-func (Sha256) Hash(password, salt string) string {
-	b := sha3.Sum256([]byte(password + salt))
-	return hex.EncodeToString(b[:])
+func (h Sha256) Hash(password, salt string) string {
+	return hex.EncodeToString(h.HashBytes([]byte(password), []byte(salt)))
+}
+
+// HashBytes creates raw hash from password.
+func (Sha256) HashBytes(password, salt []byte) []byte {
+	b := sha3.Sum256(append(append([]byte{}, password...), salt...))
+	return b[:]
 }
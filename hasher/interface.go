@@ -1,5 +1,41 @@
 package hasher
 
+// Hasher is interface for hashing algorithm.
+// Hash returns hex encoded digest.
 type Hasher interface {
 	Hash(password, salt string) string
 }
+
+// ByteHasher is interface for hashing algorithm using byte slices.
+// HashBytes returns raw digest, which is not hex encoded.
+type ByteHasher interface {
+	HashBytes(password, salt []byte) []byte
+}
+
+// Deriver is interface for key derivation function which returns raw key of outLen bytes.
+// Unlike Hasher, it returns an error when the parameters are invalid.
+type Deriver interface {
+	Derive(password, salt []byte, outLen int) ([]byte, error)
+}
+
+// Algorithm is interface for Hasher which has a stable algorithm name and cost parameters.
+// The name and parameters are recorded in the ciphertext envelope.
+type Algorithm interface {
+	Algorithm() string
+	Params() string
+}
+
+// Sizer is interface for Hasher which knows the byte size of the raw digest.
+type Sizer interface {
+	Size() int
+}
+
+// Validator is interface for Hasher which can check its cost parameters before hashing.
+type Validator interface {
+	Validate() error
+}
+
+// Restorer is interface for Hasher which can be restored from cost parameters.
+type Restorer interface {
+	WithParams(params string) (Hasher, error)
+}
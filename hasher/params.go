@@ -0,0 +1,50 @@
+package hasher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Params is cost parameters of Hasher.
+type Params map[string]uint64
+
+// ParseParams parses cost parameters formatted like `m=65536,t=1,p=4`.
+func ParseParams(params string) (Params, error) {
+	result := make(Params)
+	if params == "" {
+		return result, nil
+	}
+
+	for _, kv := range strings.Split(params, ",") {
+		parts := strings.Split(kv, "=")
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("params=[%s] is invalid format", params)
+		}
+		v, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("params=[%s] has invalid value: key=[%s]", params, parts[0])
+		}
+		result[parts[0]] = v
+	}
+	return result, nil
+}
+
+// Get returns the value of key, which must not be greater than max.
+// It returns zero when the key does not exist.
+func (p Params) Get(key string, max uint64) (uint64, error) {
+	v := p[key]
+	if v > max {
+		return 0, fmt.Errorf("param value is too large: key=[%s], value=[%d], max=[%d]", key, v, max)
+	}
+	return v, nil
+}
+
+// Limit returns the maximum value of cost parameter restored from params.
+// It is the larger of max and cost, so a Hasher which has higher cost than max raises the limit.
+func Limit(cost, max uint64) uint64 {
+	if cost > max {
+		return cost
+	}
+	return max
+}
@@ -0,0 +1,88 @@
+package hasher
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseParams(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		params     string
+		expected   Params
+		errMessage string
+	}{
+		{"", Params{}, ""},
+		{"m=65536,t=1,p=4", Params{"m": 65536, "t": 1, "p": 4}, ""},
+		{"i=4096", Params{"i": 4096}, ""},
+		{"m", nil, "params=[m] is invalid format"},
+		{"=1", nil, "params=[=1] is invalid format"},
+		{"m=1,", nil, "params=[m=1,] is invalid format"},
+		{"m=a", nil, "params=[m=a] has invalid value: key=[m]"},
+		{"m=-1", nil, "params=[m=-1] has invalid value: key=[m]"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		result, err := ParseParams(tt.params)
+		if tt.errMessage != "" {
+			a.EqualError(err, tt.errMessage, target)
+			continue
+		}
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+	}
+}
+
+func TestParams_Get(t *testing.T) {
+	a := assert.New(t)
+	p := Params{"m": 65536, "t": 1}
+
+	tests := []struct {
+		key        string
+		max        uint64
+		expected   uint64
+		errMessage string
+	}{
+		{"m", 65536, 65536, ""},
+		{"t", 255, 1, ""},
+		{"p", 255, 0, ""},
+		{"m", 255, 0, "param value is too large: key=[m], value=[65536], max=[255]"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		result, err := p.Get(tt.key, tt.max)
+		if tt.errMessage != "" {
+			a.EqualError(err, tt.errMessage, target)
+			continue
+		}
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		cost     uint64
+		max      uint64
+		expected uint64
+	}{
+		{0, 16, 16},
+		{1, 16, 16},
+		{16, 16, 16},
+		{32, 16, 32},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, Limit(tt.cost, tt.max), target)
+	}
+}
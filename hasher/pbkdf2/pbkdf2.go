@@ -3,15 +3,25 @@ package pbkdf2
 import (
 	"crypto/sha512"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"hash"
 
 	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/evalphobia/hierogolyph/hasher"
 )
 
 const (
+	algorithmName = "pbkdf2"
+
 	// see: https://godoc.org/golang.org/x/crypto/pbkdf2
 	defaultIterationSize = 4096
 	defaultKeyLength     = 32
+
+	// maximum cost parameters restored by WithParams.
+	maxIterationSize = 1 << 22
+	maxKeyLength     = 1024
 )
 
 var (
@@ -27,13 +37,90 @@ type PBKDF2 struct {
 
 // Hash creates hased text from password.
 func (p PBKDF2) Hash(password, salt string) string {
-	return hex.EncodeToString(pbkdf2.Key(
-		[]byte(password),
-		[]byte(salt),
+	return hex.EncodeToString(p.HashBytes([]byte(password), []byte(salt)))
+}
+
+// HashBytes creates raw hash from password.
+func (p PBKDF2) HashBytes(password, salt []byte) []byte {
+	return pbkdf2.Key(
+		password,
+		salt,
 		p.getIterationSize(),
 		p.getKeyLength(),
 		p.getHashFn(),
-	))
+	)
+}
+
+// Derive creates raw key of outLen bytes from password and salt.
+// KeyLength is not used.
+func (p PBKDF2) Derive(password, salt []byte, outLen int) ([]byte, error) {
+	switch {
+	case outLen <= 0:
+		return nil, hasher.ErrInvalidOutLen
+	case p.getIterationSize() <= 0:
+		return nil, fmt.Errorf("pbkdf2: iteration size is invalid: i=[%d]", p.getIterationSize())
+	}
+	return pbkdf2.Key(
+		password,
+		salt,
+		p.getIterationSize(),
+		outLen,
+		p.getHashFn(),
+	), nil
+}
+
+// Size returns byte size of the digest.
+func (p PBKDF2) Size() int {
+	return p.getKeyLength()
+}
+
+// Algorithm returns algorithm name with the hash function. (e.g. `pbkdf2-sha512`)
+// The name does not contain hash function when HashFn is unknown, which is rejected by Validate.
+func (p PBKDF2) Algorithm() string {
+	name := hasher.HashName(p.getHashFn())
+	if name == "" {
+		return algorithmName
+	}
+	return algorithmName + "-" + name
+}
+
+// Validate checks HashFn is identified by hasher.HashName, so the algorithm name can restore it.
+func (p PBKDF2) Validate() error {
+	if hasher.HashName(p.getHashFn()) == "" {
+		return errors.New("pbkdf2: hash function is unknown")
+	}
+	return nil
+}
+
+// Params returns cost parameters.
+func (p PBKDF2) Params() string {
+	return fmt.Sprintf("i=%d,l=%d", p.getIterationSize(), p.getKeyLength())
+}
+
+// WithParams returns PBKDF2 which has given cost parameters.
+// HashFn is inherited from the receiver.
+// Each parameter must not exceed the larger of the receiver's and the default maximum,
+// to prevent resource exhaustion by the parameters recorded in untrusted ciphertext.
+func (p PBKDF2) WithParams(params string) (hasher.Hasher, error) {
+	pp, err := hasher.ParseParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	iterationSize, err := pp.Get("i", hasher.Limit(uint64(p.getIterationSize()), maxIterationSize))
+	if err != nil {
+		return nil, err
+	}
+	keyLength, err := pp.Get("l", hasher.Limit(uint64(p.getKeyLength()), maxKeyLength))
+	if err != nil {
+		return nil, err
+	}
+
+	return PBKDF2{
+		HashFn:        p.HashFn,
+		IterationSize: int(iterationSize),
+		KeyLength:     int(keyLength),
+	}, nil
 }
 
 func (p PBKDF2) getHashFn() func() hash.Hash {
@@ -1,9 +1,14 @@
 package pbkdf2
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"hash"
+	"hash/fnv"
 	"testing"
 
+	"github.com/evalphobia/hierogolyph/hasher"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,3 +43,79 @@ func TestPBKDF2_Hash(t *testing.T) {
 		a.NotEqual(tt.expected, result, target, "using invalid key")
 	}
 }
+
+func TestPBKDF2_Algorithm(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		hasher   PBKDF2
+		expected string
+		valid    bool
+	}{
+		{PBKDF2{}, "pbkdf2-sha512", true},
+		{PBKDF2{HashFn: sha256.New}, "pbkdf2-sha256", true},
+		{PBKDF2{HashFn: func() hash.Hash { return fnv.New128() }}, "pbkdf2", false},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, tt.hasher.Algorithm(), target)
+		a.Equal(tt.valid, tt.hasher.Validate() == nil, target)
+	}
+}
+
+func TestPBKDF2_WithParams(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		hasher   PBKDF2
+		expected string
+	}{
+		{PBKDF2{}, "i=4096,l=32"},
+		{PBKDF2{IterationSize: 1000, KeyLength: 64}, "i=1000,l=64"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, tt.hasher.Params(), target)
+
+		restored, err := PBKDF2{}.WithParams(tt.expected)
+		a.NoError(err, target)
+		a.Equal(tt.hasher.Hash("password", "salt"), restored.Hash("password", "salt"), target)
+	}
+
+	_, err := PBKDF2{}.WithParams("i=4294967296")
+	a.Error(err)
+
+	// cost parameters exceeding the maximum are rejected, unless the receiver has higher cost.
+	_, err = PBKDF2{}.WithParams("i=2147483647")
+	a.Error(err)
+	_, err = PBKDF2{IterationSize: 1 << 23}.WithParams("i=8388608")
+	a.NoError(err)
+}
+
+func TestPBKDF2_Derive(t *testing.T) {
+	a := assert.New(t)
+	h := PBKDF2{}
+
+	tests := []struct {
+		outLen int
+	}{
+		{16},
+		{32},
+		{64},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		key, err := h.Derive([]byte("password"), []byte("salt"), tt.outLen)
+		a.NoError(err, target)
+		a.Len(key, tt.outLen, target)
+		if tt.outLen == h.Size() {
+			a.Equal(h.HashBytes([]byte("password"), []byte("salt")), key, target)
+		}
+	}
+
+	_, err := h.Derive([]byte("password"), []byte("salt"), 0)
+	a.Equal(hasher.ErrInvalidOutLen, err)
+}
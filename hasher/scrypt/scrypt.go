@@ -2,16 +2,28 @@ package scrypt
 
 import (
 	"encoding/hex"
+	"fmt"
+	"math"
 
 	"golang.org/x/crypto/scrypt"
+
+	"github.com/evalphobia/hierogolyph/hasher"
 )
 
 const (
+	algorithmName = "scrypt"
+
 	// see: https://godoc.org/golang.org/x/crypto/scrypt
 	defaultCost        = 32768
 	defaultBlockSize   = 8
 	defaultParallelism = 1
 	defaultKeyLength   = 32
+
+	// maximum cost parameters restored by WithParams.
+	maxCost        = 1 << 20
+	maxBlockSize   = 8
+	maxParallelism = 16
+	maxKeyLength   = 1024
 )
 
 // SCrypt is struct to create hash.
@@ -24,18 +36,110 @@ type SCrypt struct {
 
 // Hash creates hased text from password.
 func (s SCrypt) Hash(password, salt string) string {
+	return hex.EncodeToString(s.HashBytes([]byte(password), []byte(salt)))
+}
+
+// HashBytes creates raw hash from password.
+// It returns nil when the parameters are invalid.
+func (s SCrypt) HashBytes(password, salt []byte) []byte {
+	if s.Validate() != nil {
+		return nil
+	}
+
 	hash, err := scrypt.Key(
-		[]byte(password),
-		[]byte(salt),
+		password,
+		salt,
 		s.getCost(),
 		s.getBlockSize(),
 		s.getParallelism(),
 		s.getKeyLength(),
 	)
 	if err != nil {
-		return ""
+		return nil
+	}
+	return hash
+}
+
+// Derive creates raw key of outLen bytes from password and salt.
+// KeyLength is not used.
+func (s SCrypt) Derive(password, salt []byte, outLen int) ([]byte, error) {
+	if outLen <= 0 {
+		return nil, hasher.ErrInvalidOutLen
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return scrypt.Key(
+		password,
+		salt,
+		s.getCost(),
+		s.getBlockSize(),
+		s.getParallelism(),
+		outLen,
+	)
+}
+
+// Size returns byte size of the digest.
+func (s SCrypt) Size() int {
+	return s.getKeyLength()
+}
+
+// Validate checks the cost parameters, which makes HashBytes return nil.
+func (s SCrypt) Validate() error {
+	n, r, p := s.getCost(), s.getBlockSize(), s.getParallelism()
+	switch {
+	case n <= 1 || n&(n-1) != 0:
+		return fmt.Errorf("scrypt: cost must be > 1 and a power of 2: n=[%d]", n)
+	case r <= 0 || p <= 0 || uint64(r)*uint64(p) >= 1<<30 || r > math.MaxInt/128/p || r > math.MaxInt/256 || n > math.MaxInt/128/r:
+		return fmt.Errorf("scrypt: parameters are too large: n=[%d] r=[%d] p=[%d]", n, r, p)
+	case s.getKeyLength() <= 0:
+		return fmt.Errorf("scrypt: key length is invalid: l=[%d]", s.getKeyLength())
+	}
+	return nil
+}
+
+// Algorithm returns algorithm name.
+func (SCrypt) Algorithm() string {
+	return algorithmName
+}
+
+// Params returns cost parameters.
+func (s SCrypt) Params() string {
+	return fmt.Sprintf("n=%d,r=%d,p=%d,l=%d", s.getCost(), s.getBlockSize(), s.getParallelism(), s.getKeyLength())
+}
+
+// WithParams returns SCrypt which has given cost parameters.
+// Each parameter must not exceed the larger of the receiver's and the default maximum,
+// to prevent resource exhaustion by the parameters recorded in untrusted ciphertext.
+func (s SCrypt) WithParams(params string) (hasher.Hasher, error) {
+	p, err := hasher.ParseParams(params)
+	if err != nil {
+		return nil, err
 	}
-	return hex.EncodeToString(hash)
+
+	cost, err := p.Get("n", hasher.Limit(uint64(s.getCost()), maxCost))
+	if err != nil {
+		return nil, err
+	}
+	blockSize, err := p.Get("r", hasher.Limit(uint64(s.getBlockSize()), maxBlockSize))
+	if err != nil {
+		return nil, err
+	}
+	parallelism, err := p.Get("p", hasher.Limit(uint64(s.getParallelism()), maxParallelism))
+	if err != nil {
+		return nil, err
+	}
+	keyLength, err := p.Get("l", hasher.Limit(uint64(s.getKeyLength()), maxKeyLength))
+	if err != nil {
+		return nil, err
+	}
+
+	return SCrypt{
+		Cost:        int(cost),
+		BlockSize:   int(blockSize),
+		Parallelism: int(parallelism),
+		KeyLength:   int(keyLength),
+	}, nil
 }
 
 func (s SCrypt) getCost() int {
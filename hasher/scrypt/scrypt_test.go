@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/evalphobia/hierogolyph/hasher"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,3 +40,88 @@ func TestSCrypt_Hash(t *testing.T) {
 		a.NotEqual(tt.expected, result, target, "using invalid key")
 	}
 }
+
+func TestSCrypt_WithParams(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		hasher   SCrypt
+		expected string
+	}{
+		{SCrypt{}, "n=32768,r=8,p=1,l=32"},
+		{SCrypt{Cost: 1024, BlockSize: 4, Parallelism: 2, KeyLength: 64}, "n=1024,r=4,p=2,l=64"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, tt.hasher.Params(), target)
+
+		restored, err := SCrypt{}.WithParams(tt.expected)
+		a.NoError(err, target)
+		a.Equal(tt.hasher.Hash("password", "salt"), restored.Hash("password", "salt"), target)
+	}
+
+	_, err := SCrypt{}.WithParams("n=4294967296")
+	a.Error(err)
+
+	// cost parameters exceeding the maximum are rejected, unless the receiver has higher cost.
+	_, err = SCrypt{}.WithParams("n=2147483647")
+	a.Error(err)
+	_, err = SCrypt{Cost: 1 << 21}.WithParams("n=2097152")
+	a.NoError(err)
+}
+
+func TestSCrypt_Validate(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		hasher   SCrypt
+		expected string
+	}{
+		{SCrypt{}, ""},
+		{SCrypt{Cost: 1024, BlockSize: 4, Parallelism: 2, KeyLength: 64}, ""},
+		{SCrypt{Cost: 1}, "scrypt: cost must be > 1 and a power of 2: n=[1]"},
+		{SCrypt{Cost: 1000}, "scrypt: cost must be > 1 and a power of 2: n=[1000]"},
+		{SCrypt{BlockSize: -1}, "scrypt: parameters are too large: n=[32768] r=[-1] p=[1]"},
+		{SCrypt{BlockSize: 1 << 15, Parallelism: 1 << 15}, "scrypt: parameters are too large: n=[32768] r=[32768] p=[32768]"},
+		{SCrypt{KeyLength: -1}, "scrypt: key length is invalid: l=[-1]"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		err := tt.hasher.Validate()
+		if tt.expected == "" {
+			a.NoError(err, target)
+			a.Equal(tt.hasher.getKeyLength(), tt.hasher.Size(), target)
+			continue
+		}
+		a.EqualError(err, tt.expected, target)
+		a.Equal("", tt.hasher.Hash("password", "salt"), target)
+	}
+}
+
+func TestSCrypt_Derive(t *testing.T) {
+	a := assert.New(t)
+	h := SCrypt{}
+
+	tests := []struct {
+		outLen int
+	}{
+		{16},
+		{32},
+		{64},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		key, err := h.Derive([]byte("password"), []byte("salt"), tt.outLen)
+		a.NoError(err, target)
+		a.Len(key, tt.outLen, target)
+		if tt.outLen == h.Size() {
+			a.Equal(h.HashBytes([]byte("password"), []byte("salt")), key, target)
+		}
+	}
+
+	_, err := h.Derive([]byte("password"), []byte("salt"), 0)
+	a.Equal(hasher.ErrInvalidOutLen, err)
+}
@@ -1,11 +1,17 @@
 package hierogolyph
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/evalphobia/hierogolyph/cipher"
 	"github.com/evalphobia/hierogolyph/hasher"
 	"github.com/evalphobia/hierogolyph/hsm"
+	"github.com/evalphobia/hierogolyph/pii"
 )
 
 // Hierogolyph treats encryption and decryption.
@@ -15,11 +21,38 @@ type Hierogolyph struct {
 	Password      string
 	Salt          string
 	EncryptionKey string // generated by password and salt, used for encryption/decryption and verifying password.
+	RecoveryKey   string // generated by personal key, used for recovery when the password is lost.
+}
+
+// NewHierogolyph creates Hierogolyph from the password, salt and EncryptionKey created by CreateHierogolyph.
+// It returns ErrInvalidConfig when the config or arguments are invalid, and ErrWrongKey when EncryptionKey is broken.
+func NewHierogolyph(conf Config, password, salt, encryptionKey string) (Hierogolyph, error) {
+	if err := conf.Validate(); err != nil {
+		return Hierogolyph{}, err
+	}
+	if salt == "" {
+		return Hierogolyph{}, newError(ErrInvalidConfig, errors.New("salt must not be empty"))
+	}
+	if _, err := parseEncryptionKey(encryptionKey); err != nil {
+		return Hierogolyph{}, newError(ErrWrongKey, err)
+	}
+
+	return Hierogolyph{
+		Config:        conf,
+		Password:      password,
+		Salt:          salt,
+		EncryptionKey: encryptionKey,
+	}, nil
 }
 
 // CreateHierogolyph creates new Hierogolyph from given password, which is used for encryption.
 // (after the first encryption, don't use this constructor.)
+// It returns ErrInvalidConfig when the config is invalid.
 func CreateHierogolyph(password string, conf Config) (Hierogolyph, error) {
+	if err := conf.Validate(); err != nil {
+		return Hierogolyph{}, err
+	}
+
 	salt, err := getRandomString(20)
 	if err != nil {
 		return Hierogolyph{}, err
@@ -50,99 +83,267 @@ func (h *Hierogolyph) SetEncryptionKey() error {
 
 // Unlock creates Content Encryption Key.
 func (h Hierogolyph) Unlock() (cek string, err error) {
-	z1, z2 := createDigests(h.Password, h.Salt, h.Config.Hasher)
-	maskedCipherText, err := decodeBase64(h.EncryptionKey)
+	return h.UnlockContext(context.Background())
+}
+
+// UnlockContext creates Content Encryption Key with ctx.
+// Hasher is skipped when ctx is already done, and HSM calls are aborted when ctx is done.
+// The key is returned as string which cannot be wiped, use Open to keep the key in Session.
+func (h Hierogolyph) UnlockContext(ctx context.Context) (cek string, err error) {
+	byt, err := h.unlock(ctx)
 	if err != nil {
 		return "", err
 	}
+	defer zeroBytes(byt)
+	return string(byt), nil
+}
 
-	// get XOR between Z1 and R'
-	encryptedSecretR := xor(maskedCipherText, z1)
+// unlock creates Content Encryption Key with ctx.
+// The caller should zero the result after use.
+func (h Hierogolyph) unlock(ctx context.Context) (cek []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	secretR, err := h.Config.HSM.Decrypt(encryptedSecretR)
+	key, err := parseEncryptionKey(h.EncryptionKey)
 	if err != nil {
-		return "", err
+		return nil, newError(ErrWrongKey, err)
 	}
-
-	return createCEK(z2, secretR), nil
+	secret, z2, err := h.Config.unwrapKey(ctx, key, h.Password, h.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if key.Version == EncryptionKeyVersion0 {
+		defer zeroBytes(secret)
+		return createCEK(z2, secret), nil
+	}
+	return secret, nil
 }
 
 // Encrypt encrypts given plainText.
+// The result is a versioned envelope, which records algorithms used for the encryption.
 func (h Hierogolyph) Encrypt(plainText string) (cipherText string, err error) {
-	cek, err := h.Unlock()
+	byt, err := h.EncryptBytes([]byte(plainText))
 	if err != nil {
 		return "", err
 	}
+	return string(byt), nil
+}
 
-	fingerPrint := HashHMAC(plainText, h.Config.HMACKey)
-	fingerprintedText := fmt.Sprintf("%s.%s", encodeBase64String(plainText), encodeBase64String(fingerPrint))
+// EncryptContext encrypts given plainText with ctx.
+// ctx is used for Unlock, see UnlockContext.
+func (h Hierogolyph) EncryptContext(ctx context.Context, plainText string) (cipherText string, err error) {
+	byt, err := h.encrypt(ctx, []byte(plainText), nil)
+	if err != nil {
+		return "", err
+	}
+	return string(byt), nil
+}
 
-	cipherText, err = h.Config.Cipher.Encrypt(fingerprintedText, []byte(cek))
+// EncryptBytes encrypts given plainText.
+// The result is the same as Encrypt.
+func (h Hierogolyph) EncryptBytes(plainText []byte) (cipherText []byte, err error) {
+	return h.encrypt(context.Background(), plainText, nil)
+}
+
+// EncryptWithAAD encrypts given plainText with additional authenticated data.
+// aad binds the cipherText to the context (e.g. table, column and primary key), see AAD.
+// The same aad must be given to DecryptWithAAD.
+func (h Hierogolyph) EncryptWithAAD(plainText string, aad []byte) (cipherText string, err error) {
+	if len(aad) == 0 {
+		return "", newError(ErrInvalidConfig, errEmptyAAD)
+	}
+
+	byt, err := h.encrypt(context.Background(), []byte(plainText), aad)
+	if err != nil {
+		return "", err
+	}
+	return string(byt), nil
+}
+
+// EncryptNormalized encrypts given plainText after normalization by n.
+// Equal values are decrypted to the same canonical form. (e.g. `Foo@Example.com ` -> `foo@example.com`)
+func (h Hierogolyph) EncryptNormalized(plainText string, n pii.Normalizer) (cipherText string, err error) {
+	normalized, err := pii.Normalize(n, plainText)
 	if err != nil {
 		return "", err
 	}
+	return h.Encrypt(normalized)
+}
+
+// encrypt encrypts plainText and creates envelope.
+// aad is used only when it's not empty.
+func (h Hierogolyph) encrypt(ctx context.Context, plainText, aad []byte) (cipherText []byte, err error) {
+	cek, err := h.unlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(cek)
+	return encryptWithCEK(h.Config, h.EncryptionKey, cek, plainText, aad)
+}
+
+// encryptWithCEK encrypts plainText by cek and creates envelope.
+func encryptWithCEK(conf Config, encryptionKey string, cek, plainText, aad []byte) (cipherText []byte, err error) {
+	if conf.Cipher == nil {
+		return nil, newError(ErrInvalidConfig, errors.New("cipher is nil"))
+	}
+	if err := conf.validateDeterministic(); err != nil {
+		return nil, newError(ErrInvalidConfig, err)
+	}
+
+	payload, padding, err := conf.pad(createPayload(plainText, conf.HMACKey))
+	if err != nil {
+		return nil, err
+	}
+
+	var encrypted []byte
+	if len(aad) == 0 {
+		encrypted, err = cipher.ToByteCipher(conf.Cipher).EncryptBytes(payload, cek)
+	} else {
+		encrypted, err = encryptWithAAD(conf.Cipher, payload, cek, aad)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	encrypted := encodeBase64String(cipherText)
-	return fmt.Sprintf("%s.%s", encodeBase64String(h.EncryptionKey), encrypted), nil
+	envelope := conf.newEnvelope(encryptionKey, encrypted)
+	if envelope.Deterministic {
+		envelope = envelope.withoutWrappedKey()
+	}
+	envelope.AAD = len(aad) != 0
+	envelope.Padding = padding
+	text, err := envelope.EncodeWith(conf.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(text), nil
 }
 
 // Decrypt decrypts given cipherText.
+// Both of versioned envelope and legacy format are supported.
+// Cipher, Hasher and HSM recorded in the envelope are resolved by Config.Registry.
 func (h Hierogolyph) Decrypt(cipherText string) (plainText string, err error) {
-	encryptionKey, encryptedText, err := decodeCipherText(cipherText)
+	byt, err := h.DecryptBytes([]byte(cipherText))
 	if err != nil {
 		return "", err
 	}
+	return string(byt), nil
+}
 
-	h.EncryptionKey = encryptionKey
-	cek, err := h.Unlock()
+// DecryptContext decrypts given cipherText with ctx.
+// ctx is used for Unlock, see UnlockContext.
+func (h Hierogolyph) DecryptContext(ctx context.Context, cipherText string) (plainText string, err error) {
+	byt, err := h.decrypt(ctx, []byte(cipherText), nil)
 	if err != nil {
 		return "", err
 	}
+	return string(byt), nil
+}
+
+// DecryptBytes decrypts given cipherText.
+// The cipherText is the same format as Decrypt.
+func (h Hierogolyph) DecryptBytes(cipherText []byte) (plainText []byte, err error) {
+	return h.decrypt(context.Background(), cipherText, nil)
+}
 
-	fingerprintedText, err := h.Config.Cipher.Decrypt(encryptedText, []byte(cek))
+// DecryptSensitive decrypts given cipherText, and returns pii.Sensitive which is masked by m in logs.
+func (h Hierogolyph) DecryptSensitive(cipherText string, m pii.Masker) (pii.PII, error) {
+	plainText, err := h.Decrypt(cipherText)
 	if err != nil {
-		return "", err
+		return pii.PII{}, err
 	}
+	return pii.NewSensitive(plainText, m), nil
+}
 
-	parts := strings.Split(fingerprintedText, ".")
-	if len(parts) != 2 {
-		return "", fmt.Errorf("fingerprintedText=[%s] must have one dot `.`", fingerprintedText)
+// DecryptWithAAD decrypts given cipherText created by EncryptWithAAD.
+// It returns an error when aad is different from the one used for the encryption.
+func (h Hierogolyph) DecryptWithAAD(cipherText string, aad []byte) (plainText string, err error) {
+	if len(aad) == 0 {
+		return "", newError(ErrInvalidConfig, errEmptyAAD)
 	}
 
-	plainText, err = decodeBase64(parts[0])
+	byt, err := h.decrypt(context.Background(), []byte(cipherText), aad)
 	if err != nil {
 		return "", err
 	}
-	fingerPrint, err := decodeBase64(parts[1])
+	return string(byt), nil
+}
+
+// decrypt parses envelope and decrypts cipherText.
+func (h Hierogolyph) decrypt(ctx context.Context, cipherText, aad []byte) (plainText []byte, err error) {
+	envelope, err := parseEnvelopeWithAAD(cipherText, aad)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	h.Config, err = h.Config.resolve(envelope)
+	if err != nil {
+		return nil, err
 	}
 
-	expected := HashHMAC(plainText, h.Config.HMACKey)
-	if fingerPrint != expected {
-		return "", fmt.Errorf("HMAC finger print error: expected=[%s], actual=[%s]", expected, fingerPrint)
+	cek, err := h.unlockEnvelope(ctx, envelope)
+	if err != nil {
+		return nil, err
 	}
+	defer zeroBytes(cek)
+	return decryptWithCEK(h.Config, envelope, cek, aad)
+}
 
-	return plainText, nil
+// parseEnvelopeWithAAD parses envelope and checks aad is used.
+// aad must be empty when the cipherText is not bound to additional data, and vice versa.
+func parseEnvelopeWithAAD(cipherText, aad []byte) (Envelope, error) {
+	envelope, err := ParseEnvelope(string(cipherText))
+	if err != nil {
+		return Envelope{}, err
+	}
+	switch {
+	case envelope.AAD && len(aad) == 0:
+		return Envelope{}, errors.New("cipherText is bound to aad, use DecryptWithAAD")
+	case !envelope.AAD && len(aad) != 0:
+		return Envelope{}, errors.New("cipherText is not bound to aad")
+	}
+	return envelope, nil
 }
 
-// createEncryptionKey creates encryption key from password and salt.
+// decryptWithCEK decrypts cipherText in the envelope by cek.
+func decryptWithCEK(conf Config, envelope Envelope, cek, aad []byte) (plainText []byte, err error) {
+	if conf.Cipher == nil {
+		return nil, newError(ErrInvalidConfig, errors.New("cipher is nil"))
+	}
+
+	var payload []byte
+	if len(aad) == 0 {
+		payload, err = cipher.ToByteCipher(conf.Cipher).DecryptBytes(envelope.CipherText, cek)
+	} else {
+		payload, err = decryptWithAAD(conf.Cipher, envelope.CipherText, cek, aad)
+	}
+	if err != nil {
+		return nil, newError(ErrWrongKey, err)
+	}
+
+	if envelope.Padding.Mode != "" {
+		payload, err = unpad(payload)
+		if err != nil {
+			return nil, newError(ErrMalformedCipherText, err)
+		}
+	}
+	return parsePayload(envelope.Payload, payload, conf.HMACKey)
+}
+
+// createEncryptionKey creates encryption key version 1 from password and salt.
 func (h *Hierogolyph) createEncryptionKey() (string, error) {
-	secretR, err := getRandomBytes(32)
+	secret, err := getRandomBytes(keySecretSize)
 	if err != nil {
 		return "", err
 	}
 
-	conf := h.Config
-	z1, _ := createDigests(h.Password, h.Salt, conf.Hasher)
-	return createEncryptionKey(z1, string(secretR), conf.HSM)
+	return h.Config.createEncryptionKeyV1(h.Password, h.Salt, secret)
 }
 
-// decodeCipherText decodes from cipherText and returns encryptionKey and encryptedText.
+// decodeCipherText decodes from legacy cipherText and returns encryptionKey and encryptedText.
 func decodeCipherText(cipherText string) (encryptionKey, encryptedText string, err error) {
 	parts := strings.Split(cipherText, ".")
 	if len(parts) != 2 {
-		return "", "", fmt.Errorf("cipherText=[%s] must have one dot `.`", cipherText)
+		return "", "", fmt.Errorf("cipherText must have one dot `.`: parts=[%d]", len(parts))
 	}
 
 	encryptionKey, err = decodeBase64(parts[0])
@@ -157,32 +358,95 @@ func decodeCipherText(cipherText string) (encryptionKey, encryptedText string, e
 	return encryptionKey, encryptedText, nil
 }
 
+// digestSize is the size of Z1 and Z2.
+const digestSize = 32
+
 // createDigests creates 32byte string pair from given password and salt by hashing.
-func createDigests(password, salt string, hasher hasher.Hasher) (z1, z2 string) {
-	digest := hasher.Hash(password, salt)
-	return digest[0:32], digest[32:64]
+func createDigests(password, salt string, h hasher.Hasher) (z1, z2 string, err error) {
+	if h == nil {
+		return "", "", newError(ErrHasher, errors.New("hasher is nil"))
+	}
+	if v, ok := h.(hasher.Validator); ok {
+		if err := v.Validate(); err != nil {
+			return "", "", newError(ErrHasher, err)
+		}
+	}
+
+	digest := hashHex(password, salt, h)
+	if len(digest) < 64 {
+		return "", "", newError(ErrHasher, fmt.Errorf("digest is too short: size=[%d]", len(digest)))
+	}
+	return digest[0:32], digest[32:64], nil
 }
 
-// createEncryptionKey creates EncryptionKey from Z1 and R with HSM eryption.
-func createEncryptionKey(z1, secretR string, hsm hsm.HSM) (encryptionKey string, err error) {
-	encryptedSecretR, err := hsm.Encrypt(secretR)
+// deriveDigests creates Z1 and Z2 from given password and salt by the derivation mode of EncryptionKey.
+// keyKDFDerive uses raw key, Z1 has size bytes to mask the secret of the same size and Z2 has 32 bytes.
+// keyKDFHex uses 64 characters of hex encoded digest, and size is ignored.
+func deriveDigests(password, salt string, h hasher.Hasher, kdf byte, size int) (z1, z2 string, err error) {
+	if kdf == keyKDFHex {
+		return createDigests(password, salt, h)
+	}
+	if h == nil {
+		return "", "", newError(ErrHasher, errors.New("hasher is nil"))
+	}
+
+	key, err := hasher.ToDeriver(h).Derive([]byte(password), []byte(salt), size+digestSize)
 	if err != nil {
-		return "", err
+		return "", "", newError(ErrHasher, err)
+	}
+	defer zeroBytes(key)
+	return string(key[:size]), string(key[size:]), nil
+}
+
+// hashHex returns hex encoded digest of password and salt.
+// ByteHasher is preferred and Hasher is used as is for compatibility.
+func hashHex(password, salt string, h hasher.Hasher) string {
+	if v, ok := h.(hasher.ByteHasher); ok {
+		return hex.EncodeToString(v.HashBytes([]byte(password), []byte(salt)))
 	}
+	return h.Hash(password, salt)
+}
 
+// createEncryptionKey creates legacy EncryptionKey from Z1 and R with HSM eryption.
+func createEncryptionKey(z1, secretR string, h hsm.HSM) (encryptionKey string, err error) {
 	// get XOR between Z1 and R'
-	maskedCipherText := xor(string(encryptedSecretR), z1)
+	maskedCipherText, err := wrapSecret(h, []byte(secretR), z1, keyWrapOuter)
+	if err != nil {
+		return "", err
+	}
 	return encodeBase64(maskedCipherText), nil
 }
 
 // createCEK returns Content Encryption Key from Z2 and R.
-func createCEK(z2, secretR string) (cek string) {
-	return HashSHA256(z2 + secretR)
+// It's hex encoded SHA256 of Z2 and R, which is the same as legacy HashSHA256(z2 + secretR).
+func createCEK(z2 string, secretR []byte) (cek []byte) {
+	h := sha256.New()
+	_, _ = h.Write([]byte(z2))
+	_, _ = h.Write(secretR)
+	sum := h.Sum(nil)
+
+	cek = make([]byte, hex.EncodedLen(len(sum)))
+	hex.Encode(cek, sum)
+	return cek
+}
+
+// xorBytes gets XOR bytes between 'a' and 'b', which must have the same byte length.
+// It's used for raw Z1, while xor is kept for legacy keys.
+func xorBytes(a, b []byte) ([]byte, error) {
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("xor requires the same size: a=[%d] b=[%d]", len(a), len(b))
+	}
+	result := make([]byte, len(a))
+	for i := range a {
+		result[i] = a[i] ^ b[i]
+	}
+	return result, nil
 }
 
 // xor gets XOR bytes between 'a' and 'b'.
 // The results is based on 'a's length.
 // If 'a' is longer than 'b', 'b' will be padded by 0.
+// The padding counts runes of 'b', so 'b' must be ASCII. (e.g. hex encoded Z1 of keyKDFHex)
 func xor(a, b string) []byte {
 	byteSize := len(a)
 	paddedB := paddingLeft(string(b), byteSize, "0")
@@ -195,3 +195,33 @@ func runDecrypt(b *testing.B, conf Config) {
 		}
 	})
 }
+
+func Benchmark_SessionEncrypt(b *testing.B) {
+	conf := Config{
+		Cipher:  aesgcm.Cipher{},
+		HSM:     hsmgcm.NewMockHSM([]byte(testGCMKey256)),
+		Hasher:  argon2.Argon2{},
+		HMACKey: testHMACKey,
+	}
+	h, err := CreateHierogolyph(testHierogolyph1.Password, conf)
+	if err != nil {
+		b.Error(err)
+		return
+	}
+	s, err := h.Open()
+	if err != nil {
+		b.Error(err)
+		return
+	}
+	defer s.Close()
+
+	b.Run("445byte", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err = s.Encrypt(bechmarkText445)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
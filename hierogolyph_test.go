@@ -1,12 +1,14 @@
 package hierogolyph
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/evalphobia/hierogolyph/cipher/aesgcm"
 	"github.com/evalphobia/hierogolyph/hasher/argon2"
 	hsmgcm "github.com/evalphobia/hierogolyph/hsm/aesgcm"
+	"github.com/evalphobia/hierogolyph/pii"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -18,9 +20,9 @@ const (
 
 const (
 	// error messages
-	errInvalidCipher = "cipher: message authentication failed"
-	errDecodeBase64  = "illegal base64 data at input byte 0"
-	errEmptyKey      = "cipherText is too short: textsize=[0], noncesize=[12]"
+	errInvalidCipher = "key is wrong or cipherText is modified: hsm: cipherText is invalid: cipher: message authentication failed"
+	errDecodeBase64  = "key is wrong or cipherText is modified: illegal base64 data at input byte 0"
+	errEmptyKey      = "key is wrong or cipherText is modified: hsm: cipherText is invalid: cipherText is too short: textsize=[0], noncesize=[12]"
 )
 
 var (
@@ -157,7 +159,7 @@ func testHierogolyph(t *testing.T, a *assert.Assertions, tt testHierogolyphData)
 	h.Config.HMACKey = testHMACKey + "12345"
 	_, err = h.Decrypt(originalCipherText)
 	if a.Error(err, target) {
-		a.Contains(err.Error(), "HMAC finger print error:", target)
+		a.Contains(err.Error(), "HMAC finger print error", target)
 	}
 
 	// try different HSM
@@ -168,6 +170,38 @@ func testHierogolyph(t *testing.T, a *assert.Assertions, tt testHierogolyphData)
 	}
 }
 
+func TestNewHierogolyph(t *testing.T) {
+	a := assert.New(t)
+	data := testHierogolyph1
+
+	h, err := NewHierogolyph(testConfig, data.Password, data.Salt, data.EncryptionKey)
+	a.NoError(err)
+	plainText, err := h.Decrypt(testLegacyCipherText1)
+	a.NoError(err)
+	a.Equal("plain text", plainText)
+
+	tests := []struct {
+		conf     Config
+		password string
+		salt     string
+		ek       string
+		kind     error
+		expected string
+	}{
+		{Config{}, data.Password, data.Salt, data.EncryptionKey, ErrInvalidConfig, "config is invalid: cipher is nil"},
+		{testConfig, data.Password, "", data.EncryptionKey, ErrInvalidConfig, "config is invalid: salt must not be empty"},
+		{testConfig, data.Password, data.Salt, "!", ErrWrongKey, "key is wrong or cipherText is modified: illegal base64 data at input byte 0"},
+		{testConfig, data.Password, data.Salt, "hk1.", ErrWrongKey, "key is wrong or cipherText is modified: encryptionKey does not have required fields"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt.expected)
+		_, err := NewHierogolyph(tt.conf, tt.password, tt.salt, tt.ek)
+		a.EqualError(err, tt.expected, target)
+		a.True(errors.Is(err, tt.kind), target)
+	}
+}
+
 func TestHierogolyph_EncryptionKey(t *testing.T) {
 	a := assert.New(t)
 	tests := []struct {
@@ -197,9 +231,10 @@ func TestHierogolyph_EncryptionKey(t *testing.T) {
 			a.NoError(err, target)
 			a.NotEmpty(h.EncryptionKey, target)
 
-			decoded, err := decodeBase64(h.EncryptionKey)
-			a.NotEmpty(decoded, target)
+			key, err := parseEncryptionKey(h.EncryptionKey)
 			a.NoError(err, target)
+			a.Equal(EncryptionKeyVersion1, key.Version, target)
+			a.NotEmpty(key.Masked, target)
 		}
 	})
 
@@ -219,9 +254,10 @@ func TestHierogolyph_EncryptionKey(t *testing.T) {
 			a.NotEmpty(ek, target)
 			a.Empty(h.EncryptionKey, target)
 
-			decoded, err := decodeBase64(ek)
-			a.NotEmpty(decoded, target)
+			key, err := parseEncryptionKey(ek)
 			a.NoError(err, target)
+			a.Equal(EncryptionKeyVersion1, key.Version, target)
+			a.NotEmpty(key.Masked, target)
 			t.Logf("Password:[%s] Salt:[%s] EK:[%s]\n", tt.password, tt.salt, ek)
 		}
 	})
@@ -327,10 +363,73 @@ func TestHierogolyph_Encrypt(t *testing.T) {
 		// try empty hsm key
 		h.Config.HSM = hsmgcm.NewMockHSM(nil)
 		_, err = h.Encrypt(platinText)
-		a.EqualError(err, "crypto/aes: invalid key size 0", target)
+		a.EqualError(err, "hsm error: crypto/aes: invalid key size 0", target)
 	}
 }
 
+func TestHierogolyph_EncryptNormalized(t *testing.T) {
+	a := assert.New(t)
+	h := testHierogolyph1
+	h.Config = testConfig
+
+	tests := []struct {
+		normalizer pii.Normalizer
+		text       string
+		expected   string
+	}{
+		{nil, " Foo@Example.com ", " Foo@Example.com "},
+		{pii.Email{}, " Foo@Example.com ", "foo@example.com"},
+		{pii.SSN, "123-45-6789", "123456789"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		cipherText, err := h.EncryptNormalized(tt.text, tt.normalizer)
+		a.NoError(err, target)
+		plainText, err := h.Decrypt(cipherText)
+		a.NoError(err, target)
+		a.Equal(tt.expected, plainText, target)
+	}
+
+	_, err := h.EncryptNormalized("123-45-678", pii.SSN)
+	a.EqualError(err, "national id is invalid: size=[8]")
+}
+
+func TestHierogolyph_DecryptSensitive(t *testing.T) {
+	a := assert.New(t)
+	h := testHierogolyph1
+	h.Config = testConfig
+
+	cipherText, err := h.Encrypt("foo@example.com")
+	a.NoError(err)
+
+	tests := []struct {
+		masker   pii.Masker
+		expected string
+	}{
+		{nil, "[REDACTED]"},
+		{pii.Email{}, "f***@e***.com"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		plainText, err := h.DecryptSensitive(cipherText, tt.masker)
+		a.NoError(err, target)
+		a.Equal(tt.expected, fmt.Sprintf("%+v", plainText), target)
+		a.Equal("foo@example.com", plainText.Reveal(), target)
+
+		s, err := h.Open()
+		a.NoError(err, target)
+		plainText, err = s.DecryptSensitive(cipherText, tt.masker)
+		a.NoError(err, target)
+		a.Equal(tt.expected, plainText.String(), target)
+		a.Equal("foo@example.com", plainText.Reveal(), target)
+	}
+
+	_, err = h.DecryptSensitive("a.b.c", pii.Email{})
+	a.EqualError(err, "cipherText is malformed: cipherText must have one dot `.`: parts=[3]")
+}
+
 func TestHierogolyph_Decrypt(t *testing.T) {
 	a := assert.New(t)
 	h1 := testHierogolyph1
@@ -350,10 +449,11 @@ func TestHierogolyph_Decrypt(t *testing.T) {
 
 		// error
 		{errInvalidCipher, cipherText1, h2.Password, h2.Salt, h2.EncryptionKey},
-		{errDecodeBase64, "a.b", h1.Password, h1.Salt, h1.EncryptionKey},
+		{"cipherText is malformed: illegal base64 data at input byte 0", "a.b", h1.Password, h1.Salt, h1.EncryptionKey},
 		{errEmptyKey, ".", h1.Password, h1.Salt, h1.EncryptionKey},
-		{"cipherText=[] must have one dot `.`", "", h1.Password, h1.Salt, h1.EncryptionKey},
-		{"cipherText=[abcde] must have one dot `.`", "abcde", h1.Password, h1.Salt, h1.EncryptionKey},
+		{"cipherText is malformed: cipherText must have one dot `.`: parts=[3]", "a.b.c", h1.Password, h1.Salt, h1.EncryptionKey},
+		{"cipherText is malformed: envelope header is invalid: size=[0]", "", h1.Password, h1.Salt, h1.EncryptionKey},
+		{"cipherText is malformed: illegal base64 data at input byte 4", "abcde", h1.Password, h1.Salt, h1.EncryptionKey},
 	}
 
 	for _, tt := range tests {
@@ -378,7 +478,7 @@ func TestHierogolyph_Decrypt(t *testing.T) {
 		// try empty hsm key
 		h.Config.HSM = hsmgcm.NewMockHSM(nil)
 		_, err = h.Decrypt(tt.cipherText)
-		a.EqualError(err, "crypto/aes: invalid key size 0", target)
+		a.EqualError(err, "hsm error: crypto/aes: invalid key size 0", target)
 	}
 }
 
@@ -403,7 +503,8 @@ func TestCreateDigests(t *testing.T) {
 	hasher := argon2.Argon2{}
 	for _, tt := range tests {
 		target := fmt.Sprintf("%+v", tt)
-		z1, z2 := createDigests(tt.password, tt.salt, hasher)
+		z1, z2, err := createDigests(tt.password, tt.salt, hasher)
+		a.NoError(err, target)
 		a.Len(z1, 32, target)
 		a.Len(z2, 32, target)
 		a.Equal(tt.expected, z1+z2, target)
@@ -462,8 +563,8 @@ func TestCreateCEK(t *testing.T) {
 
 	for _, tt := range tests {
 		target := fmt.Sprintf("%+v", tt)
-		cek := createCEK(tt.a, tt.b)
-		a.Equal(tt.expected, cek, target)
+		cek := createCEK(tt.a, []byte(tt.b))
+		a.Equal(tt.expected, string(cek), target)
 	}
 }
 
@@ -496,3 +597,70 @@ func TestXOR(t *testing.T) {
 		a.Equal(string(tt.expected), string(result), target)
 	}
 }
+
+func TestXORBytes(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		a        string
+		b        string
+		expected []byte
+	}{
+		{"", "", []byte("")},
+		{"1234", "5678", []byte("\x04\x04\x04\f")},
+		// multi-byte UTF-8 sequence is not padded.
+		{"1234", "\xc3\xa9\xe3\x81", []byte("\xf2\x9b\xd0\xb5")},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		result, err := xorBytes([]byte(tt.a), []byte(tt.b))
+		a.NoError(err, target)
+		a.Equal(string(tt.expected), string(result), target)
+	}
+
+	_, err := xorBytes([]byte("1234"), []byte("123"))
+	a.EqualError(err, "xor requires the same size: a=[4] b=[3]")
+}
+
+func TestHierogolyph_EncryptBytes(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		data []byte
+	}{
+		{[]byte("")},
+		{[]byte("plain text")},
+		{[]byte{0x00, 0xff, 0x2e, 0x80}},
+	}
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		cipherText, err := h.EncryptBytes(tt.data)
+		a.NoError(err, target)
+
+		e, err := ParseEnvelope(string(cipherText))
+		a.NoError(err, target)
+		a.Equal(PayloadBinary, e.Payload, target)
+
+		plainText, err := h.DecryptBytes(cipherText)
+		a.NoError(err, target)
+		a.Equal(string(tt.data), string(plainText), target)
+
+		plainString, err := h.Decrypt(string(cipherText))
+		a.NoError(err, target)
+		a.Equal(string(tt.data), plainString, target)
+	}
+
+	// legacy format
+	h1 := testHierogolyph1
+	h1.Config = testConfig
+	cipherText1 := "ZDNOOVNDdGs1Qk5VdW50TnVTQUtMaThYOE1DTWxHV0pHTUZ5TWk3eTVXaGZaaDJiakVza2FJZkZPRDNUK3BFM01mMTU3dmhKNWlOMmgzMGp3VUFQdGc9PQ==.AsBEVSwjdTlK38BJR72naWQe5Y0IgP4QmYXbreRcd9HmZMCxt6+yCQvMSLc1rgkLD2NYUMT68aUO02vcq4oZpBbERjn0liKe8Wsmmjqnvu+XGiPwFLnQHzw86KSlKM+m5V4u4KYruiCfD7vBy5Ls0koPxRHAoUsiZ4/f79IQJjQpZLzAIA=="
+	plainText, err := h1.DecryptBytes([]byte(cipherText1))
+	a.NoError(err)
+	a.Equal("plain text", string(plainText))
+}
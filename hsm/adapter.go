@@ -0,0 +1,85 @@
+package hsm
+
+import "context"
+
+// ToByteHSM returns ByteHSM from HSM.
+// When h does not implement ByteHSM, byte slices are converted into string for h.
+func ToByteHSM(h HSM) ByteHSM {
+	if v, ok := h.(ByteHSM); ok {
+		return v
+	}
+	return byteHSM{h}
+}
+
+// byteHSM is adapter of HSM for ByteHSM.
+type byteHSM struct {
+	HSM
+}
+
+// EncryptBytes encrypts plainText.
+func (h byteHSM) EncryptBytes(plainText []byte) ([]byte, error) {
+	cipherText, err := h.Encrypt(string(plainText))
+	return []byte(cipherText), err
+}
+
+// DecryptBytes decrypts cipherText.
+func (h byteHSM) DecryptBytes(cipherText []byte) ([]byte, error) {
+	plainText, err := h.Decrypt(cipherText)
+	return []byte(plainText), err
+}
+
+// ToContextHSM returns ContextHSM from HSM.
+// When h does not implement ContextHSM, h is called in another goroutine,
+// and the result is discarded when ctx is done before the call returns.
+// The call itself is not cancelled and keeps running until it returns.
+func ToContextHSM(h HSM) ContextHSM {
+	if v, ok := h.(ContextHSM); ok {
+		return v
+	}
+	return contextHSM{ToByteHSM(h)}
+}
+
+// contextHSM is adapter of HSM for ContextHSM.
+type contextHSM struct {
+	ByteHSM
+}
+
+// EncryptContext encrypts plainText.
+func (h contextHSM) EncryptContext(ctx context.Context, plainText []byte) ([]byte, error) {
+	return callContext(ctx, func() ([]byte, error) {
+		return h.EncryptBytes(plainText)
+	})
+}
+
+// DecryptContext decrypts cipherText.
+func (h contextHSM) DecryptContext(ctx context.Context, cipherText []byte) ([]byte, error) {
+	return callContext(ctx, func() ([]byte, error) {
+		return h.DecryptBytes(cipherText)
+	})
+}
+
+// callContext calls fn and returns ctx.Err() when ctx is done before fn returns.
+// It does not stop fn, which keeps running in the goroutine until the underlying call returns.
+// Implement ContextHSM to cancel the request itself.
+func callContext(ctx context.Context, fn func() ([]byte, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		byt []byte
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		byt, err := fn()
+		ch <- result{byt, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.byt, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,109 @@
+package hsm_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/evalphobia/hierogolyph/hsm"
+	"github.com/evalphobia/hierogolyph/hsm/aesgcm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stringHSM implements only hsm.HSM.
+type stringHSM struct {
+	h *aesgcm.MockHSM
+}
+
+func (s stringHSM) Encrypt(plainText string) (string, error) {
+	return s.h.Encrypt(plainText)
+}
+
+func (s stringHSM) Decrypt(cipherByte []byte) (string, error) {
+	return s.h.Decrypt(cipherByte)
+}
+
+func TestToByteHSM(t *testing.T) {
+	a := assert.New(t)
+	mock := aesgcm.NewMockHSM([]byte("12345678901234567890123456789012"))
+
+	tests := []struct {
+		text []byte
+	}{
+		{[]byte("a")},
+		{[]byte("あいうえお")},
+		{[]byte{0x00, 0xff, 0x80}},
+		{[]byte{}},
+	}
+
+	a.Equal(mock, hsm.ToByteHSM(mock))
+
+	for _, h := range []hsm.HSM{mock, stringHSM{mock}} {
+		bh := hsm.ToByteHSM(h)
+		for _, tt := range tests {
+			target := fmt.Sprintf("%T %+v", h, tt)
+
+			cipherText, err := bh.EncryptBytes(tt.text)
+			a.NoError(err, target)
+
+			plainText, err := bh.DecryptBytes(cipherText)
+			a.NoError(err, target)
+			a.Equal(string(tt.text), string(plainText), target)
+
+			// compatible with string interface.
+			plainString, err := h.Decrypt(cipherText)
+			a.NoError(err, target)
+			a.Equal(string(tt.text), plainString, target)
+		}
+	}
+}
+
+// slowHSM blocks until release is closed.
+type slowHSM struct {
+	stringHSM
+	release chan struct{}
+}
+
+func (s slowHSM) Decrypt(cipherByte []byte) (string, error) {
+	<-s.release
+	return s.stringHSM.Decrypt(cipherByte)
+}
+
+func TestToContextHSM(t *testing.T) {
+	a := assert.New(t)
+	mock := aesgcm.NewMockHSM([]byte("12345678901234567890123456789012"))
+
+	a.Equal(mock, hsm.ToContextHSM(mock))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, h := range []hsm.HSM{mock, stringHSM{mock}} {
+		target := fmt.Sprintf("%T", h)
+		ch := hsm.ToContextHSM(h)
+
+		cipherText, err := ch.EncryptContext(context.Background(), []byte("plain text"))
+		a.NoError(err, target)
+		plainText, err := ch.DecryptContext(context.Background(), cipherText)
+		a.NoError(err, target)
+		a.Equal("plain text", string(plainText), target)
+
+		_, err = ch.EncryptContext(canceled, []byte("plain text"))
+		a.Equal(context.Canceled, err, target)
+		_, err = ch.DecryptContext(canceled, cipherText)
+		a.Equal(context.Canceled, err, target)
+	}
+
+	// pending call is aborted.
+	slow := slowHSM{stringHSM{mock}, make(chan struct{})}
+	defer close(slow.release)
+	cipherText, err := mock.EncryptBytes([]byte("plain text"))
+	a.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = hsm.ToContextHSM(slow).DecryptContext(ctx, cipherText)
+	a.Equal(context.DeadlineExceeded, err)
+}
@@ -1,10 +1,12 @@
 package aesgcm
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/evalphobia/hierogolyph/hsm"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -52,6 +54,7 @@ func TestMockHSM(t *testing.T) {
 		_, err = hsmInvalid.Decrypt([]byte(cipher1))
 		if a.Error(err, target) {
 			a.Contains(err.Error(), "cipher: message authentication failed", target)
+			a.True(errors.Is(err, hsm.ErrInvalidCipherText), target)
 		}
 
 		plainText2, err := hsmInvalid.Decrypt([]byte(cipher2))
@@ -87,3 +90,25 @@ func TestNewMockHSM(t *testing.T) {
 		}
 	}
 }
+
+func TestMockHSM_Validate(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"", false},
+		{"short", false},
+		{"1234567890123456", true},
+		{"123456789012345678901234", true},
+		{"12345678901234567890123456789012", true},
+		{"12345678901234567890123456789012XYZ", true},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		err := NewMockHSM([]byte(tt.key)).Validate()
+		a.Equal(tt.valid, err == nil, target)
+	}
+}
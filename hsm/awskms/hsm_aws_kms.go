@@ -1,19 +1,40 @@
 package awskms
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"strings"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	sdkkms "github.com/aws/aws-sdk-go/service/kms"
 	"github.com/evalphobia/aws-sdk-go-wrapper/kms"
+
+	"github.com/evalphobia/hierogolyph/hsm"
 )
 
 const (
 	encryptionPrefix = "AWSKMSx"
+	providerName     = "aws-kms"
+
+	invalidCipherTextCode = "InvalidCiphertextException"
 )
 
+// ContextClient is the subset of kmsiface.KMSAPI of aws-sdk-go, which is used by EncryptContext and DecryptContext.
+type ContextClient interface {
+	EncryptWithContext(ctx aws.Context, input *sdkkms.EncryptInput, opts ...request.Option) (*sdkkms.EncryptOutput, error)
+	DecryptWithContext(ctx aws.Context, input *sdkkms.DecryptInput, opts ...request.Option) (*sdkkms.DecryptOutput, error)
+}
+
 // HSM is struct for AWS KMS.
+// Client is used for EncryptContext and DecryptContext to cancel the request by context.
+// When Client is nil, KMS is called in another goroutine and the request is not cancelled.
 type HSM struct {
 	KMS     *kms.KMS
 	KeyName string
+	Client  ContextClient
 }
 
 // NewHSM creates new HSM.
@@ -24,6 +45,11 @@ func NewHSM(cli *kms.KMS, keyName string) *HSM {
 	}
 }
 
+// Provider returns provider name.
+func (h *HSM) Provider() string {
+	return providerName
+}
+
 // Encrypt encrypts plainText and adds prefix.
 func (h *HSM) Encrypt(plainText string) (cipherText string, err error) {
 	str, err := h.KMS.EncryptString(h.KeyName, plainText)
@@ -31,7 +57,77 @@ func (h *HSM) Encrypt(plainText string) (cipherText string, err error) {
 }
 
 // Decrypt decrypts prefixed cipherByte.
+// It returns hsm.ErrInvalidCipherText when cipherByte is not created by Encrypt (e.g. unmasked by wrong password).
 func (h *HSM) Decrypt(cipherByte []byte) (plainText string, err error) {
+	if !strings.HasPrefix(string(cipherByte), encryptionPrefix) {
+		return "", hsm.ErrInvalidCipherText
+	}
+
 	str, err := h.KMS.DecryptString(strings.TrimPrefix(string(cipherByte), encryptionPrefix))
+	if isInvalidCipherText(err) {
+		return "", fmt.Errorf("%w: %s", hsm.ErrInvalidCipherText, err.Error())
+	}
 	return str, err
 }
+
+// EncryptContext encrypts plainText and adds prefix.
+// The request is cancelled when ctx is done.
+func (h *HSM) EncryptContext(ctx context.Context, plainText []byte) (cipherText []byte, err error) {
+	if h.Client == nil {
+		return hsm.ToContextHSM(plainHSM{h}).EncryptContext(ctx, plainText)
+	}
+
+	out, err := h.Client.EncryptWithContext(ctx, &sdkkms.EncryptInput{
+		KeyId:     aws.String(h.KeyName),
+		Plaintext: plainText,
+	})
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return []byte(encryptionPrefix + base64.StdEncoding.EncodeToString(out.CiphertextBlob)), nil
+}
+
+// DecryptContext decrypts prefixed cipherText.
+// The request is cancelled when ctx is done.
+func (h *HSM) DecryptContext(ctx context.Context, cipherText []byte) (plainText []byte, err error) {
+	if h.Client == nil {
+		return hsm.ToContextHSM(plainHSM{h}).DecryptContext(ctx, cipherText)
+	}
+	if !strings.HasPrefix(string(cipherText), encryptionPrefix) {
+		return nil, hsm.ErrInvalidCipherText
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(string(cipherText), encryptionPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", hsm.ErrInvalidCipherText, err.Error())
+	}
+	out, err := h.Client.DecryptWithContext(ctx, &sdkkms.DecryptInput{
+		CiphertextBlob: blob,
+	})
+	switch {
+	case isInvalidCipherText(err):
+		return nil, fmt.Errorf("%w: %s", hsm.ErrInvalidCipherText, err.Error())
+	case err != nil:
+		return nil, contextError(ctx, err)
+	}
+	return out.Plaintext, nil
+}
+
+// plainHSM hides ContextHSM of HSM, which is used when Client is nil.
+type plainHSM struct {
+	hsm.HSM
+}
+
+// contextError returns ctx.Err() when ctx is done, since aws-sdk-go wraps it in awserr.Error.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// isInvalidCipherText reports whether err is InvalidCiphertextException of KMS.
+func isInvalidCipherText(err error) bool {
+	var e interface{ Code() string }
+	return errors.As(err, &e) && e.Code() == invalidCipherTextCode
+}
@@ -1,13 +1,20 @@
 package awskms
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	sdkkms "github.com/aws/aws-sdk-go/service/kms"
 	"github.com/evalphobia/aws-sdk-go-wrapper/config"
 	"github.com/evalphobia/aws-sdk-go-wrapper/kms"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/evalphobia/hierogolyph/hsm"
 )
 
 func TestHSM(t *testing.T) {
@@ -86,3 +93,145 @@ func TestNewHSM(t *testing.T) {
 		}
 	}
 }
+
+// testAWSError implements Code() of awserr.Error.
+type testAWSError struct {
+	code string
+}
+
+func (e testAWSError) Error() string { return e.code }
+func (e testAWSError) Code() string  { return e.code }
+
+func TestHSM_DecryptInvalidCipherText(t *testing.T) {
+	a := assert.New(t)
+
+	h := NewHSM(&kms.KMS{}, "alias/foobar")
+	tests := []struct {
+		text string
+	}{
+		{""},
+		{"a"},
+		{"AWSKMS"},
+		{"xAWSKMSx"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		_, err := h.Decrypt([]byte(tt.text))
+		a.True(errors.Is(err, hsm.ErrInvalidCipherText), target)
+	}
+}
+
+func TestIsInvalidCipherText(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{errors.New("InvalidCiphertextException"), false},
+		{testAWSError{"InvalidCiphertextException"}, true},
+		{fmt.Errorf("wrapped: %w", testAWSError{"InvalidCiphertextException"}), true},
+		{testAWSError{"NotFoundException"}, false},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, isInvalidCipherText(tt.err), target)
+	}
+}
+
+// testClient is ContextClient which reverses the bytes and blocks until ctx is done when block is true.
+type testClient struct {
+	block bool
+}
+
+func (c testClient) EncryptWithContext(ctx aws.Context, input *sdkkms.EncryptInput, opts ...request.Option) (*sdkkms.EncryptOutput, error) {
+	if c.block {
+		<-ctx.Done()
+		return nil, testAWSError{"RequestCanceled"}
+	}
+	return &sdkkms.EncryptOutput{CiphertextBlob: reverse(input.Plaintext)}, nil
+}
+
+func (c testClient) DecryptWithContext(ctx aws.Context, input *sdkkms.DecryptInput, opts ...request.Option) (*sdkkms.DecryptOutput, error) {
+	if c.block {
+		<-ctx.Done()
+		return nil, testAWSError{"RequestCanceled"}
+	}
+	if len(input.CiphertextBlob) == 0 {
+		return nil, testAWSError{"InvalidCiphertextException"}
+	}
+	return &sdkkms.DecryptOutput{Plaintext: reverse(input.CiphertextBlob)}, nil
+}
+
+func reverse(b []byte) []byte {
+	result := make([]byte, len(b))
+	for i, v := range b {
+		result[len(b)-1-i] = v
+	}
+	return result
+}
+
+func TestHSM_Context(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+
+	h := NewHSM(&kms.KMS{}, "alias/foobar")
+	h.Client = testClient{}
+
+	tests := []struct {
+		text string
+	}{
+		{"a"},
+		{"aaa"},
+		{"あいうえお"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		cipherText, err := h.EncryptContext(ctx, []byte(tt.text))
+		a.NoError(err, target)
+		a.True(strings.HasPrefix(string(cipherText), encryptionPrefix), target)
+
+		plainText, err := h.DecryptContext(ctx, cipherText)
+		a.NoError(err, target)
+		a.Equal(tt.text, string(plainText), target)
+	}
+
+	for _, text := range []string{"", "a", "AWSKMSx!", "AWSKMSx"} {
+		_, err := h.DecryptContext(ctx, []byte(text))
+		a.True(errors.Is(err, hsm.ErrInvalidCipherText), text, err)
+	}
+}
+
+func TestHSM_ContextCanceled(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		client ContextClient
+	}{
+		{testClient{block: true}},
+		{nil},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		h := NewHSM(&kms.KMS{}, "alias/foobar")
+		h.Client = tt.client
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if tt.client == nil {
+			cancel()
+		} else {
+			go cancel()
+		}
+
+		_, err := h.EncryptContext(ctx, []byte("a"))
+		a.True(errors.Is(err, context.Canceled), target, err)
+		_, err = h.DecryptContext(ctx, []byte(encryptionPrefix+"YQ=="))
+		a.True(errors.Is(err, context.Canceled), target, err)
+	}
+}
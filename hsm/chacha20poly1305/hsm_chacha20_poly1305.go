@@ -2,12 +2,16 @@ package chacha20poly1305
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 
 	"github.com/evalphobia/hierogolyph/crypto/chacha20poly1305"
+	"github.com/evalphobia/hierogolyph/hsm"
 )
 
 const (
 	encryptionPrefix = "ChaCha20x"
+	providerName     = "mock-xchacha20-poly1305"
 )
 
 // MockHSM is mock of HSM.
@@ -28,6 +32,19 @@ func NewMockHSM(key []byte) *MockHSM {
 	}
 }
 
+// Provider returns provider name.
+func (h *MockHSM) Provider() string {
+	return providerName
+}
+
+// Validate checks the key size, which must be 32 bytes.
+func (h *MockHSM) Validate() error {
+	if len(h.Key) != chacha20poly1305.KeySize {
+		return fmt.Errorf("hsm key size is invalid: size=[%d]", len(h.Key))
+	}
+	return nil
+}
+
 // Encrypt encrypts plainText and adds prefix.
 func (h *MockHSM) Encrypt(plainText string) (cipherText string, err error) {
 	byt, err := chacha20poly1305.Encrypt(plainText, h.Key)
@@ -36,6 +53,48 @@ func (h *MockHSM) Encrypt(plainText string) (cipherText string, err error) {
 
 // Decrypt decrypts prefixed cipherByte.
 func (h *MockHSM) Decrypt(cipherByte []byte) (plainText string, err error) {
-	byt, err := chacha20poly1305.Decrypt(bytes.TrimPrefix(cipherByte, []byte(encryptionPrefix)), h.Key)
+	byt, err := h.DecryptBytes(cipherByte)
 	return string(byt), err
 }
+
+// EncryptBytes encrypts plainText and adds prefix.
+func (h *MockHSM) EncryptBytes(plainText []byte) (cipherText []byte, err error) {
+	byt, err := chacha20poly1305.EncryptBytes(plainText, h.Key)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(encryptionPrefix), byt...), nil
+}
+
+// DecryptBytes decrypts prefixed cipherText.
+// It returns hsm.ErrInvalidCipherText when cipherText cannot be decrypted by the key.
+func (h *MockHSM) DecryptBytes(cipherText []byte) (plainText []byte, err error) {
+	// invalid key is not ErrInvalidCipherText.
+	if _, err := chacha20poly1305.NewAEAD(h.Key); err != nil {
+		return nil, err
+	}
+
+	byt, err := chacha20poly1305.DecryptBytes(bytes.TrimPrefix(cipherText, []byte(encryptionPrefix)), h.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", hsm.ErrInvalidCipherText, err.Error())
+	}
+	return byt, nil
+}
+
+// EncryptContext encrypts plainText and adds prefix.
+// It returns an error when ctx is already done.
+func (h *MockHSM) EncryptContext(ctx context.Context, plainText []byte) (cipherText []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return h.EncryptBytes(plainText)
+}
+
+// DecryptContext decrypts prefixed cipherText.
+// It returns an error when ctx is already done.
+func (h *MockHSM) DecryptContext(ctx context.Context, cipherText []byte) (plainText []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return h.DecryptBytes(cipherText)
+}
@@ -1,7 +1,40 @@
 package hsm
 
+import (
+	"context"
+	"errors"
+)
+
 // HSM is interface for Hardware Security Module.
 type HSM interface {
 	Encrypt(plainText string) (cipherText string, err error)
 	Decrypt(cipherByte []byte) (plainText string, err error)
 }
+
+// Provider is interface for HSM which has a stable provider name.
+// The name is recorded in the ciphertext envelope.
+type Provider interface {
+	Provider() string
+}
+
+// Validator is interface for HSM which can check its key before encryption.
+type Validator interface {
+	Validate() error
+}
+
+// ByteHSM is interface for Hardware Security Module using byte slices.
+type ByteHSM interface {
+	EncryptBytes(plainText []byte) (cipherText []byte, err error)
+	DecryptBytes(cipherText []byte) (plainText []byte, err error)
+}
+
+// ContextHSM is interface for HSM which supports context.Context.
+// Pending requests to the key service are aborted when the context is done.
+type ContextHSM interface {
+	EncryptContext(ctx context.Context, plainText []byte) (cipherText []byte, err error)
+	DecryptContext(ctx context.Context, cipherText []byte) (plainText []byte, err error)
+}
+
+// ErrInvalidCipherText is returned by HSM when cipherText cannot be decrypted by the key.
+// On Unlock, it usually means the password or salt is wrong.
+var ErrInvalidCipherText = errors.New("hsm: cipherText is invalid")
@@ -0,0 +1,140 @@
+package hierogolyph
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/evalphobia/hierogolyph/pii"
+)
+
+// RedactedValue is emitted instead of cipherText by MarshalJSON and MarshalText when Redactor is bound.
+// It's the same as pii.RedactedValue.
+const RedactedValue = pii.RedactedValue
+
+var errRedacted = errors.New("value is redacted")
+
+// MarshalText implements encoding.TextMarshaler, and returns encrypted value.
+// RedactedValue is returned when Redactor is bound.
+func (s EncryptedString) MarshalText() ([]byte, error) {
+	if s.Plain == "" {
+		return []byte{}, nil
+	}
+	return encryptText(s.encrypter, s.Plain)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, and decrypts the value.
+func (s *EncryptedString) UnmarshalText(text []byte) error {
+	plainText, err := decryptText(s.encrypter, text)
+	if err != nil {
+		return err
+	}
+	s.Plain = plainText
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, and returns encrypted value as JSON string.
+func (s EncryptedString) MarshalJSON() ([]byte, error) {
+	text, err := s.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, and decrypts JSON string.
+func (s *EncryptedString) UnmarshalJSON(data []byte) error {
+	text, err := unmarshalJSONText(data)
+	if err != nil {
+		return err
+	}
+	return s.UnmarshalText(text)
+}
+
+// MarshalText implements encoding.TextMarshaler, and returns encrypted value.
+// RedactedValue is returned when Redactor is bound.
+func (v Encrypted[T]) MarshalText() ([]byte, error) {
+	plainText, err := marshalPlain(v.Plain)
+	if err != nil {
+		return nil, err
+	}
+	return encryptText(v.encrypter, plainText)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, and decrypts the value.
+func (v *Encrypted[T]) UnmarshalText(text []byte) error {
+	plainText, err := decryptText(v.encrypter, text)
+	if err != nil {
+		return err
+	}
+	if plainText == "" {
+		var zero T
+		v.Plain = zero
+		return nil
+	}
+	return unmarshalPlain(plainText, &v.Plain)
+}
+
+// MarshalJSON implements json.Marshaler, and returns encrypted value as JSON string.
+func (v Encrypted[T]) MarshalJSON() ([]byte, error) {
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, and decrypts JSON string.
+func (v *Encrypted[T]) UnmarshalJSON(data []byte) error {
+	text, err := unmarshalJSONText(data)
+	if err != nil {
+		return err
+	}
+	return v.UnmarshalText(text)
+}
+
+// encryptText encrypts plainText by Encrypter.
+// EncodingBinary is not valid text, so the envelope is wrapped in EncodingBase64, which Decrypt also accepts.
+func encryptText(e Encrypter, plainText string) ([]byte, error) {
+	e, err := resolveEncrypter(e)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherText, err := e.Encrypt(plainText)
+	if err != nil {
+		return nil, err
+	}
+	if detectEncoding(cipherText) == EncodingBinary {
+		return []byte(encodeBase64String(cipherText)), nil
+	}
+	return []byte(cipherText), nil
+}
+
+// decryptText decrypts cipherText created by encryptText.
+// RedactedValue returns an error, unless Redactor is bound to leave the value empty.
+func decryptText(e Encrypter, cipherText []byte) (string, error) {
+	if len(cipherText) == 0 {
+		return "", nil
+	}
+
+	e, err := resolveEncrypter(e)
+	if _, ok := e.(Redactor); !ok && string(cipherText) == RedactedValue {
+		return "", errRedacted
+	}
+	if err != nil {
+		return "", err
+	}
+	return e.Decrypt(string(cipherText))
+}
+
+// unmarshalJSONText returns text of JSON string, null is treated as empty text.
+func unmarshalJSONText(data []byte) ([]byte, error) {
+	var text *string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return nil, err
+	}
+	if text == nil {
+		return nil, nil
+	}
+	return []byte(*text), nil
+}
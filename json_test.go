@@ -0,0 +1,148 @@
+package hierogolyph
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMessage struct {
+	Email   EncryptedString        `json:"email"`
+	Profile Encrypted[testProfile] `json:"profile"`
+	Empty   EncryptedString        `json:"empty"`
+}
+
+func TestEncryptedString_MarshalJSON(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+
+	msg := testMessage{
+		Email:   EncryptedString{Plain: "foo@example.com"},
+		Profile: Encrypted[testProfile]{Plain: testProfile{Name: "foo", Age: 20}},
+	}
+	msg.Email.Bind(h)
+	msg.Profile.Bind(h)
+
+	byt, err := json.Marshal(msg)
+	a.NoError(err)
+	a.NotContains(string(byt), "foo")
+
+	// cipherText of Encrypt is emitted.
+	var raw map[string]string
+	a.NoError(json.Unmarshal(byt, &raw))
+	plainText, err := h.Decrypt(raw["email"])
+	a.NoError(err)
+	a.Equal("foo@example.com", plainText)
+	a.Equal("", raw["empty"])
+
+	result := testMessage{}
+	result.Email.Bind(h)
+	result.Profile.Bind(h)
+	a.NoError(json.Unmarshal(byt, &result))
+	a.Equal("foo@example.com", result.Email.Plain)
+	a.Equal(testProfile{Name: "foo", Age: 20}, result.Profile.Plain)
+	a.Equal("", result.Empty.Plain)
+
+	// null
+	a.NoError(json.Unmarshal([]byte(`{"email":null,"profile":null}`), &result))
+	a.Equal("", result.Email.Plain)
+	a.Equal(testProfile{}, result.Profile.Plain)
+
+	// another key
+	other, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	result.Email.Bind(other)
+	err = json.Unmarshal(byt, &result)
+	a.True(errors.Is(err, ErrWrongKey))
+
+	// not bound
+	err = json.Unmarshal(byt, &testMessage{})
+	a.EqualError(err, "encrypter is not bound")
+}
+
+func TestEncryptedString_MarshalJSONBinary(t *testing.T) {
+	a := assert.New(t)
+
+	conf := testConfig
+	conf.Encoding = EncodingBinary
+	h, err := CreateHierogolyph("password", conf)
+	a.NoError(err)
+
+	msg := testMessage{
+		Email:   EncryptedString{Plain: "foo@example.com"},
+		Profile: Encrypted[testProfile]{Plain: testProfile{Name: "foo", Age: 20}},
+	}
+	msg.Email.Bind(h)
+	msg.Profile.Bind(h)
+
+	byt, err := json.Marshal(msg)
+	a.NoError(err)
+	a.NotContains(string(byt), `\ufffd`)
+
+	// binary envelope is wrapped in base64.
+	var raw map[string]string
+	a.NoError(json.Unmarshal(byt, &raw))
+	a.Equal(EncodingBase64, detectEncoding(raw["email"]))
+
+	result := testMessage{}
+	result.Email.Bind(h)
+	result.Profile.Bind(h)
+	a.NoError(json.Unmarshal(byt, &result))
+	a.Equal("foo@example.com", result.Email.Plain)
+	a.Equal(testProfile{Name: "foo", Age: 20}, result.Profile.Plain)
+}
+
+func TestEncryptedString_MarshalJSONRedacted(t *testing.T) {
+	a := assert.New(t)
+
+	msg := testMessage{
+		Email:   EncryptedString{Plain: "foo@example.com"},
+		Profile: Encrypted[testProfile]{Plain: testProfile{Name: "foo", Age: 20}},
+	}
+
+	// Encrypter is required unless Redactor is bound.
+	_, err := json.Marshal(msg)
+	a.True(errors.Is(err, errNoEncrypter))
+
+	msg.Email.Bind(Redactor{})
+	msg.Profile.Bind(Redactor{})
+	byt, err := json.Marshal(msg)
+	a.NoError(err)
+	a.Equal(`{"email":"[REDACTED]","profile":"[REDACTED]","empty":""}`, string(byt))
+
+	text, err := msg.Email.MarshalText()
+	a.NoError(err)
+	a.Equal(RedactedValue, string(text))
+
+	// redacted value is not unmarshalled by Encrypter.
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	result := testMessage{}
+	result.Email.Bind(h)
+	result.Profile.Bind(h)
+	err = json.Unmarshal(byt, &result)
+	a.EqualError(err, "value is redacted")
+	err = json.Unmarshal(byt, &testMessage{})
+	a.EqualError(err, "value is redacted")
+
+	// Redactor leaves it empty, and the rest of the document is unmarshalled.
+	cipherText, err := h.Encrypt("foo@example.com")
+	a.NoError(err)
+	data := []byte(`{"email":"` + cipherText + `","profile":"[REDACTED]","empty":""}`)
+	result = testMessage{}
+	result.Email.Bind(h)
+	result.Profile.Bind(Redactor{})
+	result.Profile.Plain = testProfile{Name: "bar"}
+	a.NoError(json.Unmarshal(data, &result))
+	a.Equal("foo@example.com", result.Email.Plain)
+	a.Equal(testProfile{}, result.Profile.Plain)
+
+	// Redactor cannot decrypt cipherText.
+	result.Email.Bind(Redactor{})
+	err = json.Unmarshal(data, &result)
+	a.EqualError(err, "redactor cannot decrypt cipherText")
+}
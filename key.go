@@ -0,0 +1,605 @@
+package hierogolyph
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/evalphobia/hierogolyph/hsm"
+)
+
+const (
+	// EncryptionKeyVersion0 is legacy EncryptionKey, `base64(HSM(R) xor Z1)`.
+	// CEK is created from Z2 and R, so it's changed by password.
+	EncryptionKeyVersion0 = 0
+	// EncryptionKeyVersion1 is EncryptionKey which wraps CEK by HSM and Z1, `hk1.base64(fields)`.
+	// Password can be changed without re-encryption.
+	EncryptionKeyVersion1 = 1
+)
+
+const (
+	encryptionKeyPrefixV1 = "hk1."
+	keySecretSize         = 32
+	keyIDSize             = 8
+	verifierSize          = 16
+)
+
+var (
+	keyIDInfo    = []byte("hierogolyph key id")
+	verifierInfo = []byte("hierogolyph verifier")
+)
+
+// field tags of EncryptionKey version 1.
+const (
+	keyTagID byte = iota + 1
+	keyTagMasked
+	keyTagVerifier
+	keyTagSalt
+	keyTagKDF
+	keyTagWrap
+	keyTagHSM
+)
+
+// derivation modes of Z1 and Z2, recorded in EncryptionKey version 1.
+const (
+	// keyKDFHex slices hex encoded digest of Hasher, used by legacy keys.
+	keyKDFHex byte = iota
+	// keyKDFDerive uses raw key derived by hasher.Deriver.
+	keyKDFDerive
+)
+
+// wrapping modes of the secret, recorded in EncryptionKey version 1.
+const (
+	// keyWrapOuter masks the secret encrypted by HSM, `HSM(secret) xor Z1`, used by legacy keys.
+	keyWrapOuter byte = iota
+	// keyWrapInner encrypts the masked secret by HSM, `HSM(secret xor Z1)`.
+	// HSM can be rotated without the password.
+	keyWrapInner
+)
+
+// encryptionKey is parsed EncryptionKey.
+type encryptionKey struct {
+	Version  int
+	KeyID    []byte // identifier of CEK, it's not changed by password.
+	Masked   []byte // `HSM(secret) xor Z1` or `HSM(secret xor Z1)` by Wrap.
+	Verifier []byte // verifier of the password, `HMAC(secret, Z2)`.
+	Salt     string // salt of the password, it's used when the salt is not stored outside (e.g. RecoveryKey).
+	KDF      byte   // derivation mode of Z1 and Z2, keyKDFHex or keyKDFDerive.
+	Wrap     byte   // wrapping mode of the secret, keyWrapOuter or keyWrapInner.
+	HSM      string // provider name of HSM which wraps the secret, it's resolved by Config.Registry.
+}
+
+// parseEncryptionKey parses text form of EncryptionKey.
+func parseEncryptionKey(text string) (encryptionKey, error) {
+	if !strings.HasPrefix(text, encryptionKeyPrefixV1) {
+		masked, err := decodeBase64(text)
+		if err != nil {
+			return encryptionKey{}, err
+		}
+		return encryptionKey{
+			Version: EncryptionKeyVersion0,
+			Masked:  []byte(masked),
+		}, nil
+	}
+
+	byt, err := decodeBase64(strings.TrimPrefix(text, encryptionKeyPrefixV1))
+	if err != nil {
+		return encryptionKey{}, err
+	}
+	return parseEncryptionKeyBinary([]byte(byt))
+}
+
+// parseEncryptionKeyBinary parses binary form of EncryptionKey version 1.
+func parseEncryptionKeyBinary(byt []byte) (encryptionKey, error) {
+	k := encryptionKey{
+		Version: EncryptionKeyVersion1,
+	}
+	for len(byt) > 0 {
+		tag, value, rest, err := readField(byt)
+		if err != nil {
+			return encryptionKey{}, fmt.Errorf("encryptionKey field is broken: tag=[%d]", byt[0])
+		}
+		byt = rest
+
+		switch tag {
+		case keyTagID:
+			k.KeyID = value
+		case keyTagMasked:
+			k.Masked = value
+		case keyTagVerifier:
+			k.Verifier = value
+		case keyTagSalt:
+			k.Salt = string(value)
+		case keyTagKDF:
+			if len(value) != 1 || value[0] != keyKDFDerive {
+				return encryptionKey{}, fmt.Errorf("encryptionKey field is broken: tag=[%d]", tag)
+			}
+			k.KDF = value[0]
+		case keyTagWrap:
+			if len(value) != 1 || value[0] != keyWrapInner {
+				return encryptionKey{}, fmt.Errorf("encryptionKey field is broken: tag=[%d]", tag)
+			}
+			k.Wrap = value[0]
+		case keyTagHSM:
+			k.HSM = string(value)
+		default:
+			return encryptionKey{}, fmt.Errorf("encryptionKey has unknown field: tag=[%d]", tag)
+		}
+	}
+
+	// Masked is empty when the key is only a reference of the key id (e.g. Session of KeyBundle).
+	// Otherwise, Verifier is required.
+	if len(k.KeyID) != keyIDSize || (len(k.Masked) != 0 && len(k.Verifier) != verifierSize) {
+		return encryptionKey{}, errors.New("encryptionKey does not have required fields")
+	}
+	return k, nil
+}
+
+// String returns text form of EncryptionKey.
+func (k encryptionKey) String() string {
+	if k.Version == EncryptionKeyVersion0 {
+		return encodeBase64(k.Masked)
+	}
+	return encryptionKeyPrefixV1 + encodeBase64(k.marshalBinary())
+}
+
+// marshalBinary returns binary form of EncryptionKey version 1.
+func (k encryptionKey) marshalBinary() []byte {
+	byt := appendField(nil, keyTagID, k.KeyID)
+	if len(k.Masked) != 0 {
+		byt = appendField(byt, keyTagMasked, k.Masked)
+	}
+	if len(k.Verifier) != 0 {
+		byt = appendField(byt, keyTagVerifier, k.Verifier)
+	}
+	if k.Salt != "" {
+		byt = appendField(byt, keyTagSalt, []byte(k.Salt))
+	}
+	if k.KDF != keyKDFHex {
+		byt = appendField(byt, keyTagKDF, []byte{k.KDF})
+	}
+	if k.Wrap != keyWrapOuter {
+		byt = appendField(byt, keyTagWrap, []byte{k.Wrap})
+	}
+	if k.HSM != "" {
+		byt = appendField(byt, keyTagHSM, []byte(k.HSM))
+	}
+	return byt
+}
+
+// isReference reports whether the key has only the key id.
+func (k encryptionKey) isReference() bool {
+	return k.Version == EncryptionKeyVersion1 && len(k.Masked) == 0
+}
+
+// newReferenceKey creates encryptionKey which has only the key id.
+func newReferenceKey(keyID []byte) encryptionKey {
+	return encryptionKey{
+		Version: EncryptionKeyVersion1,
+		KeyID:   keyID,
+	}
+}
+
+// verify reports whether the secret and Z2 are matched with the key in constant time.
+func (k encryptionKey) verify(secret []byte, z2 string) bool {
+	switch {
+	case k.Version == EncryptionKeyVersion0:
+		// the secret is verified by HSM.
+		return true
+	}
+
+	validID := hmac.Equal(k.KeyID, createKeyID(secret))
+	validVerifier := hmac.Equal(k.Verifier, createVerifier(secret, z2))
+	return validID && validVerifier
+}
+
+// VerifyPassword reports whether the password is correct for Salt and EncryptionKey.
+// The password is compared with the verifier in EncryptionKey in constant time after the key is unwrapped by HSM.
+// It returns ErrHSM when HSM is unavailable.
+func (h Hierogolyph) VerifyPassword(password string) (bool, error) {
+	key, err := parseEncryptionKey(h.EncryptionKey)
+	if err != nil {
+		return false, newError(ErrWrongKey, err)
+	}
+	secret, _, err := h.Config.unwrapKey(context.Background(), key, password, h.Salt)
+	switch {
+	case errors.Is(err, ErrWrongKey):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	zeroBytes(secret)
+	return true, nil
+}
+
+// unwrapKey unwraps the secret in the key by password and salt, and verifies it.
+func (c Config) unwrapKey(ctx context.Context, key encryptionKey, password, salt string) (secret []byte, z2 string, err error) {
+	if key.isReference() {
+		return nil, "", newError(ErrWrongKey, errors.New("encryptionKey does not have wrapped key"))
+	}
+
+	h, err := c.resolveKeyHSM(key)
+	if err != nil {
+		return nil, "", err
+	}
+	masked, size := key.Masked, digestSize
+	if key.Wrap == keyWrapInner {
+		masked, err = hsmDecrypt(ctx, h, key.Masked)
+		if err != nil {
+			return nil, "", err
+		}
+		defer zeroBytes(masked)
+		size = len(masked)
+	}
+	z1, z2, err := deriveDigests(password, salt, c.Hasher, key.KDF, size)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err = unmaskSecret(ctx, h, masked, z1, key.Wrap, key.KDF)
+	if err != nil {
+		return nil, "", err
+	}
+	if !key.verify(secret, z2) {
+		zeroBytes(secret)
+		return nil, "", newError(ErrWrongKey, errors.New("password is not verified"))
+	}
+	return secret, z2, nil
+}
+
+// resolveKeyHSM returns HSM recorded in the key by Config.Registry.
+// Config.HSM is used when the key or Config.HSM does not have the provider name.
+func (c Config) resolveKeyHSM(key encryptionKey) (hsm.HSM, error) {
+	name := c.hsmName()
+	if key.HSM == "" || name == "" || key.HSM == name {
+		return c.HSM, nil
+	}
+	return c.getRegistry().HSM(key.HSM)
+}
+
+// ChangePassword changes password without re-encryption of existing cipherTexts.
+// CEK is wrapped again with the new password and a new salt, so Salt and EncryptionKey must be saved again.
+// Legacy EncryptionKey is upgraded to version 1, which keeps the same CEK.
+func (h *Hierogolyph) ChangePassword(oldPassword, newPassword string) error {
+	old := *h
+	old.Password = oldPassword
+	cek, err := old.unlock(context.Background())
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(cek)
+
+	salt, err := getRandomString(20)
+	if err != nil {
+		return err
+	}
+	ek, err := h.Config.createEncryptionKeyV1(newPassword, salt, cek)
+	if err != nil {
+		return err
+	}
+
+	h.Password = newPassword
+	h.Salt = salt
+	h.EncryptionKey = ek
+	return nil
+}
+
+// unlockEnvelope returns CEK for the envelope.
+// EncryptionKey in the envelope is used, unless it has the same key id as Hierogolyph.EncryptionKey.
+// The reference key, which has only the key id, is resolved against Hierogolyph.EncryptionKey.
+// When the envelope has legacy EncryptionKey which cannot be unlocked,
+// Hierogolyph.EncryptionKey is tried, because it can be upgraded by ChangePassword.
+func (h Hierogolyph) unlockEnvelope(ctx context.Context, e Envelope) (cek []byte, err error) {
+	if e.EncryptionKey == h.EncryptionKey {
+		return h.unlock(ctx)
+	}
+	if key, err := parseEncryptionKey(e.EncryptionKey); err == nil && key.isReference() {
+		return h.unlockReference(ctx, key)
+	}
+
+	current, err := parseEncryptionKey(h.EncryptionKey)
+	if err != nil || current.Version != EncryptionKeyVersion1 {
+		h.EncryptionKey = e.EncryptionKey
+		return h.unlock(ctx)
+	}
+
+	if h.hasSameKeyID(e.EncryptionKey) {
+		return h.unlock(ctx)
+	}
+	embedded, err := parseEncryptionKey(e.EncryptionKey)
+	if err != nil {
+		return nil, newError(ErrWrongKey, err)
+	}
+
+	old := h
+	old.EncryptionKey = e.EncryptionKey
+	cek, err = old.unlock(ctx)
+	switch {
+	case err == nil,
+		embedded.Version != EncryptionKeyVersion0,
+		!errors.Is(err, ErrWrongKey) && !errors.Is(err, ErrHSM):
+		return cek, err
+	}
+	return h.unlock(ctx)
+}
+
+// unlockReference returns CEK of Hierogolyph.EncryptionKey when it has the key id of the reference key.
+// The key id is compared with CEK, so that the legacy EncryptionKey, which does not record the key id,
+// can decrypt cipherTexts of its deterministic encryption and KeyBundle sessions.
+func (h Hierogolyph) unlockReference(ctx context.Context, key encryptionKey) (cek []byte, err error) {
+	cek, err = h.unlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(key.KeyID, createKeyID(cek)) {
+		zeroBytes(cek)
+		return nil, newError(ErrWrongKey, errors.New("cipherText is encrypted by another EncryptionKey"))
+	}
+	return cek, nil
+}
+
+// createEncryptionKeyV1 creates EncryptionKey version 1 which wraps the secret by password, salt and Config.HSM.
+func (c Config) createEncryptionKeyV1(password, salt string, secret []byte) (string, error) {
+	key, err := c.newEncryptionKeyV1(password, salt, secret)
+	if err != nil {
+		return "", err
+	}
+	return key.String(), nil
+}
+
+// newEncryptionKeyV1 creates encryptionKey version 1 which wraps the secret by password, salt and Config.HSM.
+// Z1 is derived in the size of the secret, so that the whole secret is masked before HSM encryption.
+func (c Config) newEncryptionKeyV1(password, salt string, secret []byte) (encryptionKey, error) {
+	z1, z2, err := deriveDigests(password, salt, c.Hasher, keyKDFDerive, len(secret))
+	if err != nil {
+		return encryptionKey{}, err
+	}
+	masked, err := wrapSecret(c.HSM, secret, z1, keyWrapInner)
+	if err != nil {
+		return encryptionKey{}, err
+	}
+	return encryptionKey{
+		Version:  EncryptionKeyVersion1,
+		KeyID:    createKeyID(secret),
+		Masked:   masked,
+		Verifier: createVerifier(secret, z2),
+		KDF:      keyKDFDerive,
+		Wrap:     keyWrapInner,
+		HSM:      c.hsmName(),
+	}, nil
+}
+
+// createKeyID returns identifier of the secret.
+func createKeyID(secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(keyIDInfo)
+	return mac.Sum(nil)[:keyIDSize]
+}
+
+// createVerifier returns verifier of the password from the secret and Z2.
+func createVerifier(secret []byte, z2 string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(verifierInfo)
+	_, _ = mac.Write([]byte(z2))
+	return mac.Sum(nil)[:verifierSize]
+}
+
+// wrapSecret encrypts the secret by HSM and masks it by Z1 in the wrapping mode.
+func wrapSecret(h hsm.HSM, secret []byte, z1 string, mode byte) ([]byte, error) {
+	if mode == keyWrapOuter {
+		encrypted, err := hsmEncrypt(h, secret)
+		if err != nil {
+			return nil, err
+		}
+		return xor(string(encrypted), z1), nil
+	}
+
+	// Z1 is raw bytes derived in the size of the secret.
+	masked, err := xorBytes(secret, []byte(z1))
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(masked)
+	return hsmEncrypt(h, masked)
+}
+
+// unmaskSecret unmasks the secret by Z1 in the wrapping mode and the derivation mode.
+// keyWrapOuter decrypts the unmasked secret by HSM,
+// while keyWrapInner requires masked decrypted by HSM in advance, because Z1 is derived in its size.
+func unmaskSecret(ctx context.Context, h hsm.HSM, masked []byte, z1 string, mode, kdf byte) ([]byte, error) {
+	switch {
+	case mode == keyWrapOuter:
+		return hsmDecrypt(ctx, h, xor(string(masked), z1))
+	case kdf == keyKDFHex:
+		// hex encoded Z1 of the key wrapped again by older versions.
+		return xor(string(masked), z1), nil
+	}
+
+	secret, err := xorBytes(masked, []byte(z1))
+	if err != nil {
+		return nil, newError(ErrWrongKey, err)
+	}
+	return secret, nil
+}
+
+// hsmEncrypt encrypts byt by HSM.
+func hsmEncrypt(h hsm.HSM, byt []byte) ([]byte, error) {
+	if h == nil {
+		return nil, newError(ErrHSM, errors.New("hsm is nil"))
+	}
+	encrypted, err := hsm.ToByteHSM(h).EncryptBytes(byt)
+	if err != nil {
+		return nil, newError(ErrHSM, err)
+	}
+	return encrypted, nil
+}
+
+// hsmDecrypt decrypts byt by HSM.
+func hsmDecrypt(ctx context.Context, h hsm.HSM, byt []byte) ([]byte, error) {
+	if h == nil {
+		return nil, newError(ErrHSM, errors.New("hsm is nil"))
+	}
+	decrypted, err := hsm.ToContextHSM(h).DecryptContext(ctx, byt)
+	switch {
+	case errors.Is(err, hsm.ErrInvalidCipherText):
+		return nil, newError(ErrWrongKey, err)
+	case err != nil:
+		return nil, newError(ErrHSM, err)
+	}
+	return decrypted, nil
+}
+
+// RotateHSM wraps the key again by newHSM, and sets newHSM to Config.HSM.
+// Password and salt are not changed, and existing cipherTexts can be decrypted by newHSM.
+// EncryptionKey is changed, so it must be saved again.
+// The password is not required, so it can be used by a batch job with only EncryptionKey and HSMs.
+// EncryptionKey created by older versions is masked by Z1 outside of HSM, so the password is required
+// and version 1 is upgraded to be rotated without the password next time.
+// RecoveryKey is wrapped again too when it's set, so it must be saved again.
+func (h *Hierogolyph) RotateHSM(newHSM hsm.HSM) error {
+	ek, err := h.rewrapEncryptionKey(h.EncryptionKey, newHSM)
+	if err != nil {
+		return err
+	}
+	rk := h.RecoveryKey
+	if rk != "" {
+		rk, err = h.rewrapRecoveryKey(rk, newHSM)
+		if err != nil {
+			return err
+		}
+	}
+
+	h.EncryptionKey = ek
+	h.RecoveryKey = rk
+	h.Config.HSM = newHSM
+	return nil
+}
+
+// rewrapRecoveryKey wraps RecoveryKey again by newHSM.
+// RecoveryKey masked by Z1 outside of HSM is not changed, because the personal key is required.
+func (h Hierogolyph) rewrapRecoveryKey(recoveryKey string, newHSM hsm.HSM) (string, error) {
+	key, err := parseEncryptionKey(recoveryKey)
+	if err != nil {
+		return "", newError(ErrWrongKey, err)
+	}
+	if key.Wrap != keyWrapInner {
+		return recoveryKey, nil
+	}
+	return h.rewrapEncryptionKey(recoveryKey, newHSM)
+}
+
+// RotateHSMCipherText returns cipherText whose EncryptionKey is wrapped again by newHSM.
+// Encrypted data is not changed, and the result can be decrypted by the same password and salt.
+// HSM recorded in the cipherText is resolved by Config.Registry.
+func (h Hierogolyph) RotateHSMCipherText(cipherText string, newHSM hsm.HSM) (string, error) {
+	envelope, err := ParseEnvelope(cipherText)
+	if err != nil {
+		return "", err
+	}
+	h.Config, err = h.Config.resolve(envelope)
+	if err != nil {
+		return "", err
+	}
+
+	ek := envelope.EncryptionKey
+	key, _ := parseEncryptionKey(ek)
+	switch {
+	case key.isReference():
+		// deterministic cipherText does not have the wrapped key.
+		return cipherText, nil
+	case key.Wrap == keyWrapOuter && h.hasSameKeyID(ek):
+		// the key in the cipherText can be wrapped by another password (e.g. after ChangePassword).
+		ek = h.EncryptionKey
+	}
+	envelope.EncryptionKey, err = h.rewrapEncryptionKey(ek, newHSM)
+	if err != nil {
+		return "", err
+	}
+	if envelope.Version != EnvelopeVersion0 {
+		envelope.HSM = Config{HSM: newHSM}.hsmName()
+	}
+	return envelope.EncodeWith(detectEncoding(cipherText))
+}
+
+// rewrapEncryptionKey unwraps EncryptionKey by Config.HSM and wraps it by newHSM.
+// The password is used only for the key of keyWrapOuter.
+func (h Hierogolyph) rewrapEncryptionKey(encryptionKey string, newHSM hsm.HSM) (string, error) {
+	key, err := parseEncryptionKey(encryptionKey)
+	switch {
+	case err != nil:
+		return "", newError(ErrWrongKey, err)
+	case key.isReference():
+		return "", newError(ErrWrongKey, errors.New("encryptionKey does not have wrapped key"))
+	}
+	oldHSM, err := h.Config.resolveKeyHSM(key)
+	if err != nil {
+		return "", err
+	}
+
+	if key.Wrap == keyWrapInner {
+		masked, err := hsmDecrypt(context.Background(), oldHSM, key.Masked)
+		if err != nil {
+			return "", err
+		}
+		defer zeroBytes(masked)
+		key.Masked, err = hsmEncrypt(newHSM, masked)
+		if err != nil {
+			return "", err
+		}
+		key.HSM = Config{HSM: newHSM}.hsmName()
+		return key.String(), nil
+	}
+
+	z1, z2, err := deriveDigests(h.Password, h.Salt, h.Config.Hasher, key.KDF, digestSize)
+	if err != nil {
+		return "", err
+	}
+	secret, err := unmaskSecret(context.Background(), oldHSM, key.Masked, z1, key.Wrap, key.KDF)
+	if err != nil {
+		return "", err
+	}
+	defer zeroBytes(secret)
+	if !key.verify(secret, z2) {
+		return "", newError(ErrWrongKey, errors.New("password is not verified"))
+	}
+
+	if key.Version == EncryptionKeyVersion1 {
+		// Z1 of the old mode may be shorter than the secret, so the key is created again with the same password and salt.
+		conf := h.Config
+		conf.HSM = newHSM
+		newKey, err := conf.newEncryptionKeyV1(h.Password, h.Salt, secret)
+		if err != nil {
+			return "", err
+		}
+		newKey.Salt = key.Salt
+		return newKey.String(), nil
+	}
+	key.Masked, err = wrapSecret(newHSM, secret, z1, key.Wrap)
+	if err != nil {
+		return "", err
+	}
+	return key.String(), nil
+}
+
+// hasSameKeyID reports whether encryptionKey has the same key id as Hierogolyph.EncryptionKey.
+func (h Hierogolyph) hasSameKeyID(encryptionKey string) bool {
+	current, err := parseEncryptionKey(h.EncryptionKey)
+	if err != nil || current.Version != EncryptionKeyVersion1 {
+		return false
+	}
+	key, err := parseEncryptionKey(encryptionKey)
+	if err != nil || key.Version != EncryptionKeyVersion1 {
+		return false
+	}
+	return hmac.Equal(key.KeyID, current.KeyID)
+}
+
+// zeroBytes overwrites byt with zero.
+func zeroBytes(byt []byte) {
+	for i := range byt {
+		byt[i] = 0
+	}
+}
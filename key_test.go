@@ -0,0 +1,460 @@
+package hierogolyph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/hasher/scrypt"
+	hsmchacha "github.com/evalphobia/hierogolyph/hsm/chacha20poly1305"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testLegacyCipherText1 = "ZDNOOVNDdGs1Qk5VdW50TnVTQUtMaThYOE1DTWxHV0pHTUZ5TWk3eTVXaGZaaDJiakVza2FJZkZPRDNUK3BFM01mMTU3dmhKNWlOMmgzMGp3VUFQdGc9PQ==.AsBEVSwjdTlK38BJR72naWQe5Y0IgP4QmYXbreRcd9HmZMCxt6+yCQvMSLc1rgkLD2NYUMT68aUO02vcq4oZpBbERjn0liKe8Wsmmjqnvu+XGiPwFLnQHzw86KSlKM+m5V4u4KYruiCfD7vBy5Ls0koPxRHAoUsiZ4/f79IQJjQpZLzAIA=="
+
+func TestParseEncryptionKey(t *testing.T) {
+	a := assert.New(t)
+
+	v1 := encryptionKey{
+		Version:  EncryptionKeyVersion1,
+		KeyID:    []byte("12345678"),
+		Masked:   []byte("masked"),
+		Verifier: []byte("1234567890123456"),
+	}
+
+	tests := []struct {
+		text     string
+		expected encryptionKey
+	}{
+		{testHierogolyph1.EncryptionKey, encryptionKey{Version: EncryptionKeyVersion0, Masked: []byte(mustDecodeBase64(testHierogolyph1.EncryptionKey))}},
+		{"", encryptionKey{Version: EncryptionKeyVersion0, Masked: []byte{}}},
+		{v1.String(), v1},
+		{encryptionKey{Version: EncryptionKeyVersion1, KeyID: v1.KeyID, Masked: v1.Masked, Verifier: v1.Verifier, KDF: keyKDFDerive}.String(),
+			encryptionKey{Version: EncryptionKeyVersion1, KeyID: v1.KeyID, Masked: v1.Masked, Verifier: v1.Verifier, KDF: keyKDFDerive}},
+		{newReferenceKey(v1.KeyID).String(), newReferenceKey(v1.KeyID)},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		key, err := parseEncryptionKey(tt.text)
+		a.NoError(err, target)
+		a.Equal(tt.expected, key, target)
+		a.Equal(tt.text, key.String(), target)
+	}
+
+	errTests := []struct {
+		errMessage string
+		text       string
+	}{
+		{"illegal base64 data at input byte 0", "!"},
+		{"illegal base64 data at input byte 0", "hk1.!"},
+		{"encryptionKey does not have required fields", "hk1."},
+		{"encryptionKey does not have required fields", "hk1." + encodeBase64(appendField(nil, keyTagID, []byte("1234")))},
+		{"encryptionKey does not have required fields", encryptionKey{Version: EncryptionKeyVersion1, KeyID: v1.KeyID, Masked: v1.Masked}.String()},
+		{"encryptionKey does not have required fields", encryptionKey{Version: EncryptionKeyVersion1, KeyID: v1.KeyID, Masked: v1.Masked, Verifier: []byte("short")}.String()},
+		{"encryptionKey field is broken: tag=[1]", "hk1." + encodeBase64([]byte{1, 5})},
+		{"encryptionKey has unknown field: tag=[99]", "hk1." + encodeBase64([]byte{99, 0})},
+		{"encryptionKey field is broken: tag=[5]", "hk1." + encodeBase64(appendField(v1.marshalBinary(), keyTagKDF, []byte{9}))},
+	}
+	for _, tt := range errTests {
+		target := fmt.Sprintf("%+v", tt)
+		_, err := parseEncryptionKey(tt.text)
+		a.EqualError(err, tt.errMessage, target)
+	}
+}
+
+func TestHierogolyph_KDF(t *testing.T) {
+	a := assert.New(t)
+
+	// new key uses hasher.Deriver.
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	key, err := parseEncryptionKey(h.EncryptionKey)
+	a.NoError(err)
+	a.Equal(keyKDFDerive, key.KDF)
+
+	// every byte of the secret is masked by raw Z1.
+	cek, err := h.Unlock()
+	a.NoError(err)
+	masked, err := hsmDecrypt(context.Background(), testConfig.HSM, key.Masked)
+	a.NoError(err)
+	z1, _, err := deriveDigests(h.Password, h.Salt, testConfig.Hasher, keyKDFDerive, len(masked))
+	a.NoError(err)
+	unmasked, err := xorBytes(masked, []byte(z1))
+	a.NoError(err)
+	a.Equal(cek, string(unmasked))
+
+	// key created from hex digest.
+	secret := []byte("12345678901234567890123456789012")
+	z1, z2, err := createDigests("password", "salt", testConfig.Hasher)
+	a.NoError(err)
+	masked, err = wrapSecret(testConfig.HSM, secret, z1, keyWrapOuter)
+	a.NoError(err)
+	hexKey := encryptionKey{
+		Version:  EncryptionKeyVersion1,
+		KeyID:    createKeyID(secret),
+		Masked:   masked,
+		Verifier: createVerifier(secret, z2),
+	}
+	legacy := Hierogolyph{
+		Config:        testConfig,
+		Password:      "password",
+		Salt:          "salt",
+		EncryptionKey: hexKey.String(),
+	}
+	cek, err = legacy.Unlock()
+	a.NoError(err)
+	a.Equal(string(secret), cek)
+	ok, err := legacy.VerifyPassword("password")
+	a.NoError(err)
+	a.True(ok)
+
+	// rotation upgrades the mode and the wrapping.
+	newHSM := hsmchacha.NewMockHSM([]byte("12345678901234567890123456789012"))
+	a.NoError(legacy.RotateHSM(newHSM))
+	key, err = parseEncryptionKey(legacy.EncryptionKey)
+	a.NoError(err)
+	a.Equal(keyKDFDerive, key.KDF)
+	a.Equal(keyWrapInner, key.Wrap)
+	cek, err = legacy.Unlock()
+	a.NoError(err)
+	a.Equal(string(secret), cek)
+
+	// changing password upgrades the mode.
+	a.NoError(legacy.ChangePassword("password", "new password"))
+	key, err = parseEncryptionKey(legacy.EncryptionKey)
+	a.NoError(err)
+	a.Equal(keyKDFDerive, key.KDF)
+	cek, err = legacy.Unlock()
+	a.NoError(err)
+	a.Equal(string(secret), cek)
+
+	// raw derivation fails with invalid parameters.
+	h.Config.Hasher = scrypt.SCrypt{Cost: 3}
+	_, err = h.Unlock()
+	a.EqualError(err, "hasher error: scrypt: cost must be > 1 and a power of 2: n=[3]")
+}
+
+func TestHierogolyph_ChangePassword(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	cipherText1, err := h.Encrypt("plain text 1")
+	a.NoError(err)
+	cek, err := h.Unlock()
+	a.NoError(err)
+
+	// wrong password
+	err = h.ChangePassword("wrong password", "new password")
+	a.True(errors.Is(err, ErrWrongKey))
+
+	oldSalt := h.Salt
+	oldKey := h.EncryptionKey
+	a.NoError(h.ChangePassword("password", "new password"))
+	a.Equal("new password", h.Password)
+	a.NotEqual(oldSalt, h.Salt)
+	a.NotEqual(oldKey, h.EncryptionKey)
+
+	// CEK is not changed.
+	newCEK, err := h.Unlock()
+	a.NoError(err)
+	a.Equal(cek, newCEK)
+
+	cipherText2, err := h.Encrypt("plain text 2")
+	a.NoError(err)
+	a.NoError(h.ChangePassword("new password", "new password 2"))
+
+	// existing cipherTexts are decrypted by the new password.
+	h2 := Hierogolyph{
+		Config:        testConfig,
+		Password:      "new password 2",
+		Salt:          h.Salt,
+		EncryptionKey: h.EncryptionKey,
+	}
+	s, err := h2.Open()
+	a.NoError(err)
+	for text, cipherText := range map[string]string{"plain text 1": cipherText1, "plain text 2": cipherText2} {
+		plainText, err := h2.Decrypt(cipherText)
+		a.NoError(err, text)
+		a.Equal(text, plainText, text)
+
+		plainText, err = s.Decrypt(cipherText)
+		a.NoError(err, text)
+		a.Equal(text, plainText, text)
+	}
+
+	// stream
+	encrypted := encryptStream(t, h, []byte("stream text"), 1)
+	a.NoError(h.ChangePassword("new password 2", "new password 3"))
+	r, err := h.NewDecryptReader(bytes.NewReader(encrypted))
+	a.NoError(err)
+	result, err := ioutil.ReadAll(r)
+	a.NoError(err)
+	a.Equal("stream text", string(result))
+
+	// old password cannot be used.
+	h2.Password = "password"
+	h2.Salt = oldSalt
+	_, err = h2.Decrypt(cipherText2)
+	a.True(errors.Is(err, ErrWrongKey))
+}
+
+func TestHierogolyph_ChangePasswordLegacy(t *testing.T) {
+	a := assert.New(t)
+
+	h := testHierogolyph1
+	h.Config = testConfig
+
+	// legacy key in the versioned envelope.
+	cipherText2, err := h.Encrypt("plain text 2")
+	a.NoError(err)
+
+	a.NoError(h.ChangePassword("password", "new password"))
+	key, err := parseEncryptionKey(h.EncryptionKey)
+	a.NoError(err)
+	a.Equal(EncryptionKeyVersion1, key.Version)
+
+	// the whole CEK is masked by Z1, so HSM alone cannot recover it.
+	cek, err := h.Unlock()
+	a.NoError(err)
+	masked, err := hsmDecrypt(context.Background(), testConfig.HSM, key.Masked)
+	a.NoError(err)
+	a.Len(masked, len(cek))
+	a.NotEqual(cek, string(masked))
+	a.NotEqual(cek[:digestSize], string(xor(string(masked[:digestSize]), padding("0", digestSize))))
+
+	s, err := h.Open()
+	a.NoError(err)
+	for text, cipherText := range map[string]string{"plain text": testLegacyCipherText1, "plain text 2": cipherText2} {
+		plainText, err := h.Decrypt(cipherText)
+		a.NoError(err, text)
+		a.Equal(text, plainText, text)
+
+		plainText, err = s.Decrypt(cipherText)
+		a.NoError(err, text)
+		a.Equal(text, plainText, text)
+	}
+
+	// the legacy key is still used with the old password.
+	legacy := testHierogolyph1
+	legacy.Config = testConfig
+	plainText, err := legacy.Decrypt(cipherText2)
+	a.NoError(err)
+	a.Equal("plain text 2", plainText)
+
+	// another key cannot decrypt legacy cipherText.
+	other, err := CreateHierogolyph("new password", testConfig)
+	a.NoError(err)
+	_, err = other.Decrypt(testLegacyCipherText1)
+	a.True(errors.Is(err, ErrWrongKey))
+	s, err = other.Open()
+	a.NoError(err)
+	_, err = s.Decrypt(testLegacyCipherText1)
+	a.True(errors.Is(err, ErrWrongKey))
+}
+
+func mustDecodeBase64(text string) string {
+	s, err := decodeBase64(text)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func TestHierogolyph_RotateHSM(t *testing.T) {
+	a := assert.New(t)
+	newHSM := hsmchacha.NewMockHSM([]byte("12345678901234567890123456789012"))
+
+	legacy := testHierogolyph1
+	legacy.Config = testConfig
+	created, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+
+	for _, h := range []Hierogolyph{legacy, created} {
+		target := h.EncryptionKey
+		cek, err := h.Unlock()
+		a.NoError(err, target)
+		cipherText, err := h.Encrypt("plain text")
+		a.NoError(err, target)
+
+		// wrong password
+		wrong := h
+		wrong.Password = "wrong password"
+		if h.EncryptionKey == legacy.EncryptionKey {
+			a.True(errors.Is(wrong.RotateHSM(newHSM), ErrWrongKey), target)
+			_, err = wrong.RotateHSMCipherText(cipherText, newHSM)
+			a.True(errors.Is(err, ErrWrongKey), target)
+		} else {
+			// the password is not used.
+			a.NoError(wrong.RotateHSM(newHSM), target)
+		}
+
+		// cipherText
+		rotatedText, err := h.RotateHSMCipherText(cipherText, newHSM)
+		a.NoError(err, target)
+		e, err := ParseEnvelope(rotatedText)
+		a.NoError(err, target)
+		a.Equal("mock-xchacha20-poly1305", e.HSM, target)
+
+		oldKey := h.EncryptionKey
+		a.NoError(h.RotateHSM(newHSM), target)
+		a.Equal(newHSM, h.Config.HSM, target)
+		a.NotEqual(oldKey, h.EncryptionKey, target)
+
+		// CEK is not changed.
+		newCEK, err := h.Unlock()
+		a.NoError(err, target)
+		a.Equal(cek, newCEK, target)
+
+		// decrypted by the same password and salt.
+		h2 := Hierogolyph{
+			Config:   testConfig,
+			Password: h.Password,
+			Salt:     h.Salt,
+		}
+		h2.Config.HSM = newHSM
+		plainText, err := h2.Decrypt(rotatedText)
+		a.NoError(err, target)
+		a.Equal("plain text", plainText, target)
+
+		// old HSM cannot be used.
+		h2.Config.HSM = testConfig.HSM
+		h2.Config.Registry = NewEmptyRegistry()
+		_, err = h2.Decrypt(rotatedText)
+		a.Error(err, target)
+	}
+
+	// legacy cipherText
+	h := legacy
+	rotatedText, err := h.RotateHSMCipherText(testLegacyCipherText1, newHSM)
+	a.NoError(err)
+	a.NoError(h.RotateHSM(newHSM))
+	plainText, err := h.Decrypt(rotatedText)
+	a.NoError(err)
+	a.Equal("plain text", plainText)
+}
+
+func TestHierogolyph_RotateHSMWithoutPassword(t *testing.T) {
+	a := assert.New(t)
+	newHSM := hsmchacha.NewMockHSM([]byte("12345678901234567890123456789012"))
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	cek, err := h.Unlock()
+	a.NoError(err)
+	cipherText, err := h.Encrypt("plain text")
+	a.NoError(err)
+
+	// batch job has only EncryptionKey and HSMs.
+	batch := Hierogolyph{
+		Config:        testConfig,
+		EncryptionKey: h.EncryptionKey,
+	}
+	rotatedText, err := batch.RotateHSMCipherText(cipherText, newHSM)
+	a.NoError(err)
+	a.NoError(batch.RotateHSM(newHSM))
+	a.NotEqual(h.EncryptionKey, batch.EncryptionKey)
+
+	user := Hierogolyph{
+		Config:        testConfig,
+		Password:      "password",
+		Salt:          h.Salt,
+		EncryptionKey: batch.EncryptionKey,
+	}
+	user.Config.HSM = newHSM
+	user.Config.Registry = NewEmptyRegistry()
+	newCEK, err := user.Unlock()
+	a.NoError(err)
+	a.Equal(cek, newCEK)
+	plainText, err := user.Decrypt(rotatedText)
+	a.NoError(err)
+	a.Equal("plain text", plainText)
+
+	// wrong password is detected after the rotation.
+	user.Password = "wrong password"
+	_, err = user.Unlock()
+	a.True(errors.Is(err, ErrWrongKey))
+
+	// legacy key requires the password.
+	legacy := Hierogolyph{
+		Config:        testConfig,
+		EncryptionKey: testHierogolyph1.EncryptionKey,
+	}
+	a.True(errors.Is(legacy.RotateHSM(newHSM), ErrWrongKey))
+}
+
+// unavailableHSM always fails.
+type unavailableHSM struct{}
+
+func (unavailableHSM) Encrypt(plainText string) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func (unavailableHSM) Decrypt(cipherByte []byte) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func TestHierogolyph_VerifyPassword(t *testing.T) {
+	a := assert.New(t)
+
+	created, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	key, err := parseEncryptionKey(created.EncryptionKey)
+	a.NoError(err)
+	a.Len(key.Verifier, verifierSize)
+
+	changed := created
+	a.NoError(changed.ChangePassword("password", "new password"))
+	legacy := testHierogolyph1
+	legacy.Config = testConfig
+
+	tests := []struct {
+		h        Hierogolyph
+		password string
+		expected bool
+	}{
+		{created, "password", true},
+		{created, "Password", false},
+		{created, "", false},
+		{changed, "new password", true},
+		{changed, "password", false},
+		{legacy, "password", true},
+		{legacy, "password2", false},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%s %s", tt.h.EncryptionKey, tt.password)
+		ok, err := tt.h.VerifyPassword(tt.password)
+		a.NoError(err, target)
+		a.Equal(tt.expected, ok, target)
+	}
+
+	// HSM is unavailable.
+	h := created
+	h.Config.HSM = unavailableHSM{}
+	ok, err := h.VerifyPassword("password")
+	a.False(ok)
+	a.True(errors.Is(err, ErrHSM))
+
+	// broken EncryptionKey
+	h = created
+	h.EncryptionKey = "!"
+	ok, err = h.VerifyPassword("password")
+	a.False(ok)
+	a.True(errors.Is(err, ErrWrongKey))
+
+	// verifier is checked after HSM.
+	h = created
+	key.Verifier = make([]byte, verifierSize)
+	h.EncryptionKey = key.String()
+	ok, err = h.VerifyPassword("password")
+	a.NoError(err)
+	a.False(ok)
+	_, err = h.Unlock()
+	a.True(errors.Is(err, ErrWrongKey))
+}
@@ -0,0 +1,140 @@
+package hierogolyph
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// padding modes.
+const (
+	// PaddingPowerOfTwo pads the payload to the next power of two.
+	// The size is at least BlockSize, and multiple of MaxSize when it exceeds MaxSize.
+	PaddingPowerOfTwo = "pow2"
+	// PaddingBlock pads the payload to multiple of BlockSize.
+	PaddingBlock = "block"
+	// PaddingFixed pads all payloads to MaxSize, and longer payload cannot be encrypted.
+	PaddingFixed = "fixed"
+)
+
+// maxPaddingSize is the limit of BlockSize and MaxSize.
+const maxPaddingSize = maxSegmentSize
+
+// paddingMark is the first byte of padding, followed by zeros. (ISO/IEC 7816-4)
+const paddingMark = 0x80
+
+// Padding hides the length of plainText by padding the payload before encryption.
+// The padding is inside the encrypted payload, so it's authenticated by Cipher and removed on decryption.
+// Padding is not used for streaming encryption.
+// The sizes are bytes of the payload, which is plainText, 32 bytes of HMAC and at least 1 byte of padding.
+type Padding struct {
+	// Mode is PaddingPowerOfTwo, PaddingBlock or PaddingFixed.
+	Mode      string
+	BlockSize int
+	MaxSize   int
+}
+
+// paddedSize returns the payload size after padding.
+// size includes the mark byte.
+func (p Padding) paddedSize(size int) (int, error) {
+	if err := p.validate(); err != nil {
+		return 0, err
+	}
+
+	switch p.Mode {
+	case PaddingPowerOfTwo:
+		if p.MaxSize > 0 && size > p.MaxSize {
+			return roundUp(size, p.MaxSize), nil
+		}
+		padded := 1
+		for padded < size || padded < p.BlockSize {
+			padded <<= 1
+		}
+		if p.MaxSize > 0 && padded > p.MaxSize {
+			return p.MaxSize, nil
+		}
+		return padded, nil
+	case PaddingBlock:
+		return roundUp(size, p.BlockSize), nil
+	default: // PaddingFixed
+		if size > p.MaxSize {
+			return 0, fmt.Errorf("payload is too long for padding: size=[%d] max=[%d]", size, p.MaxSize)
+		}
+		return p.MaxSize, nil
+	}
+}
+
+// validate checks the mode and sizes.
+func (p Padding) validate() error {
+	invalid := p.BlockSize < 0 || p.MaxSize < 0 || p.BlockSize > maxPaddingSize || p.MaxSize > maxPaddingSize
+	switch p.Mode {
+	case PaddingPowerOfTwo:
+	case PaddingBlock:
+		invalid = invalid || p.BlockSize == 0
+	case PaddingFixed:
+		invalid = invalid || p.MaxSize == 0
+	default:
+		return fmt.Errorf("padding mode is unknown: mode=[%s]", p.Mode)
+	}
+
+	if invalid {
+		return fmt.Errorf("padding size is invalid: block=[%d] max=[%d]", p.BlockSize, p.MaxSize)
+	}
+	return nil
+}
+
+// pad appends the mark byte and zeros to payload.
+func (p Padding) pad(payload []byte) ([]byte, error) {
+	size, err := p.paddedSize(len(payload) + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	padded := make([]byte, size)
+	copy(padded, payload)
+	padded[len(payload)] = paddingMark
+	return padded, nil
+}
+
+// unpad removes padding appended by pad.
+func unpad(padded []byte) ([]byte, error) {
+	i := len(padded) - 1
+	for i >= 0 && padded[i] == 0 {
+		i--
+	}
+	if i < 0 || padded[i] != paddingMark {
+		return nil, errors.New("padding is invalid")
+	}
+	return padded[:i], nil
+}
+
+// marshalBinary returns binary form of the padding for the envelope.
+func (p Padding) marshalBinary() []byte {
+	byt := encodeUvarint(uint64(p.BlockSize))
+	byt = append(byt, encodeUvarint(uint64(p.MaxSize))...)
+	return append(byt, p.Mode...)
+}
+
+// parsePaddingBinary parses binary form of the padding.
+func parsePaddingBinary(byt []byte) (Padding, error) {
+	blockSize, n := binary.Uvarint(byt)
+	if n <= 0 || blockSize > maxPaddingSize {
+		return Padding{}, errors.New("padding field is broken")
+	}
+	byt = byt[n:]
+
+	maxSize, n := binary.Uvarint(byt)
+	if n <= 0 || maxSize > maxPaddingSize || n == len(byt) {
+		return Padding{}, errors.New("padding field is broken")
+	}
+	return Padding{
+		Mode:      string(byt[n:]),
+		BlockSize: int(blockSize),
+		MaxSize:   int(maxSize),
+	}, nil
+}
+
+// roundUp returns the smallest multiple of unit which is equal to or greater than size.
+func roundUp(size, unit int) int {
+	return (size + unit - 1) / unit * unit
+}
@@ -0,0 +1,159 @@
+package hierogolyph
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPadding_paddedSize(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		padding  Padding
+		size     int
+		expected int
+	}{
+		{Padding{Mode: PaddingPowerOfTwo}, 1, 1},
+		{Padding{Mode: PaddingPowerOfTwo}, 33, 64},
+		{Padding{Mode: PaddingPowerOfTwo}, 64, 64},
+		{Padding{Mode: PaddingPowerOfTwo, BlockSize: 64}, 33, 64},
+		{Padding{Mode: PaddingPowerOfTwo, BlockSize: 64}, 65, 128},
+		{Padding{Mode: PaddingPowerOfTwo, MaxSize: 100}, 65, 100},
+		{Padding{Mode: PaddingPowerOfTwo, MaxSize: 100}, 101, 200},
+		{Padding{Mode: PaddingPowerOfTwo, MaxSize: 100}, 250, 300},
+		{Padding{Mode: PaddingBlock, BlockSize: 16}, 1, 16},
+		{Padding{Mode: PaddingBlock, BlockSize: 16}, 16, 16},
+		{Padding{Mode: PaddingBlock, BlockSize: 16}, 17, 32},
+		{Padding{Mode: PaddingFixed, MaxSize: 100}, 1, 100},
+		{Padding{Mode: PaddingFixed, MaxSize: 100}, 100, 100},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		size, err := tt.padding.paddedSize(tt.size)
+		a.NoError(err, target)
+		a.Equal(tt.expected, size, target)
+	}
+}
+
+func TestPadding_paddedSizeError(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		padding  Padding
+		expected string
+	}{
+		{Padding{}, "padding mode is unknown: mode=[]"},
+		{Padding{Mode: "pkcs7"}, "padding mode is unknown: mode=[pkcs7]"},
+		{Padding{Mode: PaddingPowerOfTwo, BlockSize: -1}, "padding size is invalid: block=[-1] max=[0]"},
+		{Padding{Mode: PaddingPowerOfTwo, MaxSize: maxPaddingSize + 1}, "padding size is invalid: block=[0] max=[16777217]"},
+		{Padding{Mode: PaddingBlock}, "padding size is invalid: block=[0] max=[0]"},
+		{Padding{Mode: PaddingFixed}, "padding size is invalid: block=[0] max=[0]"},
+		{Padding{Mode: PaddingFixed, MaxSize: 16}, "payload is too long for padding: size=[17] max=[16]"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		_, err := tt.padding.paddedSize(17)
+		a.EqualError(err, tt.expected, target)
+	}
+}
+
+func TestPadding_pad(t *testing.T) {
+	a := assert.New(t)
+	p := Padding{Mode: PaddingBlock, BlockSize: 8}
+
+	tests := []struct {
+		payload  []byte
+		expected []byte
+	}{
+		{[]byte{}, []byte{0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{[]byte("abc"), []byte{'a', 'b', 'c', 0x80, 0, 0, 0, 0}},
+		{[]byte("abc\x80\x00"), []byte{'a', 'b', 'c', 0x80, 0, 0x80, 0, 0}},
+		{[]byte("1234567"), []byte{'1', '2', '3', '4', '5', '6', '7', 0x80}},
+		{[]byte("12345678"), []byte{'1', '2', '3', '4', '5', '6', '7', '8', 0x80, 0, 0, 0, 0, 0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		padded, err := p.pad(tt.payload)
+		a.NoError(err, target)
+		a.Equal(tt.expected, padded, target)
+
+		payload, err := unpad(padded)
+		a.NoError(err, target)
+		a.Equal(tt.payload, payload, target)
+	}
+
+	for _, padded := range [][]byte{{}, {0, 0}, {'a', 0}, {0x80, 1}} {
+		_, err := unpad(padded)
+		a.EqualError(err, "padding is invalid", fmt.Sprint(padded))
+	}
+}
+
+func TestParsePaddingBinary(t *testing.T) {
+	a := assert.New(t)
+
+	p := Padding{Mode: PaddingPowerOfTwo, BlockSize: 16, MaxSize: 4096}
+	result, err := parsePaddingBinary(p.marshalBinary())
+	a.NoError(err)
+	a.Equal(p, result)
+
+	for _, byt := range [][]byte{{}, {16}, {16, 0}, {0xff, 0xff, 0xff, 0xff, 0x0f, 0, 'a'}} {
+		_, err := parsePaddingBinary(byt)
+		a.EqualError(err, "padding field is broken", fmt.Sprint(byt))
+	}
+}
+
+func TestHierogolyph_EncryptPadding(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		padding Padding
+		texts   []string
+	}{
+		{Padding{Mode: PaddingPowerOfTwo, BlockSize: 64}, []string{"", "1234", "123-45-6789"}},
+		{Padding{Mode: PaddingPowerOfTwo}, []string{"John", "Alexander"}},
+		{Padding{Mode: PaddingBlock, BlockSize: 32}, []string{"a", "foo@example.com"}},
+		{Padding{Mode: PaddingFixed, MaxSize: 128}, []string{"", "foo@example.com", "Hubert Blaine Wolfeschlegelsteinhausenbergerdorff"}},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		h := testHierogolyph1
+		h.Config = testConfig
+		h.Config.Padding = &tt.padding
+
+		size := -1
+		for _, text := range tt.texts {
+			cipherText, err := h.Encrypt(text)
+			a.NoError(err, target)
+			if size >= 0 {
+				a.Len(cipherText, size, target)
+			}
+			size = len(cipherText)
+
+			e, err := ParseEnvelope(cipherText)
+			a.NoError(err, target)
+			a.Equal(tt.padding, e.Padding, target)
+
+			// decryption doesn't depend on Config.Padding.
+			h2 := h
+			h2.Config.Padding = nil
+			plainText, err := h2.Decrypt(cipherText)
+			a.NoError(err, target)
+			a.Equal(text, plainText, target)
+		}
+	}
+
+	h := testHierogolyph1
+	h.Config = testConfig
+	h.Config.Padding = &Padding{Mode: PaddingFixed, MaxSize: 64}
+	_, err := h.Encrypt("12345678901234567890123456789012")
+	a.EqualError(err, "payload is too long for padding: size=[65] max=[64]")
+	h.Config.Padding = &Padding{Mode: "pkcs7"}
+	_, err = h.Encrypt("plain text")
+	a.EqualError(err, "padding mode is unknown: mode=[pkcs7]")
+}
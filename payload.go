@@ -0,0 +1,86 @@
+package hierogolyph
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// PayloadText is `base64(plainText).base64(HMAC)`, used by legacy format.
+	PayloadText = 0
+	// PayloadBinary is `plainText || HMAC`.
+	PayloadBinary = 1
+	// PayloadStream is segmented data following the envelope, see NewEncryptWriter.
+	PayloadStream = 2
+)
+
+// createPayload creates payload from plainText and its HMAC fingerprint.
+func createPayload(plainText []byte, hmacKey string) []byte {
+	payload := make([]byte, 0, len(plainText)+sha256.Size)
+	payload = append(payload, plainText...)
+	return append(payload, hashHMACBytes(plainText, hmacKey)...)
+}
+
+// parsePayload returns plainText from payload after verifying HMAC fingerprint.
+// It returns ErrIntegrity when HMAC fingerprint is not matched.
+func parsePayload(format int, payload []byte, hmacKey string) ([]byte, error) {
+	plainText, err := parsePayloadFormat(format, payload, hmacKey)
+	if err != nil {
+		return nil, newError(ErrMalformedCipherText, err)
+	}
+	return plainText, nil
+}
+
+// parsePayloadFormat parses payload of the format.
+func parsePayloadFormat(format int, payload []byte, hmacKey string) ([]byte, error) {
+	switch format {
+	case PayloadText:
+		plainText, err := parseTextPayload(string(payload), hmacKey)
+		return []byte(plainText), err
+	case PayloadBinary:
+		return parseBinaryPayload(payload, hmacKey)
+	default:
+		return nil, fmt.Errorf("payload format=[%d] is not supported", format)
+	}
+}
+
+// parseBinaryPayload parses `plainText || HMAC`.
+func parseBinaryPayload(payload []byte, hmacKey string) ([]byte, error) {
+	size := len(payload) - sha256.Size
+	if size < 0 {
+		return nil, fmt.Errorf("payload is too short: size=[%d]", len(payload))
+	}
+
+	plainText := payload[:size]
+	if !hmac.Equal(payload[size:], hashHMACBytes(plainText, hmacKey)) {
+		return nil, newError(ErrIntegrity, errors.New("HMAC finger print error"))
+	}
+	return plainText, nil
+}
+
+// parseTextPayload parses `base64(plainText).base64(HMAC)`.
+func parseTextPayload(fingerprintedText, hmacKey string) (string, error) {
+	parts := strings.Split(fingerprintedText, ".")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("payload must have one dot `.`: parts=[%d]", len(parts))
+	}
+
+	plainText, err := decodeBase64(parts[0])
+	if err != nil {
+		return "", err
+	}
+	fingerPrint, err := decodeBase64(parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	expected := HashHMAC(plainText, hmacKey)
+	if !hmac.Equal([]byte(fingerPrint), []byte(expected)) {
+		return "", newError(ErrIntegrity, errors.New("HMAC finger print error"))
+	}
+
+	return plainText, nil
+}
@@ -0,0 +1,50 @@
+package hierogolyph
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayload(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		text []byte
+	}{
+		{[]byte("")},
+		{[]byte("a")},
+		{[]byte("あいうえお")},
+		{[]byte("a.b.c")},
+		{[]byte{0x00, 0xff, 0x80}},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		payload := createPayload(tt.text, testHMACKey)
+		a.Len(payload, len(tt.text)+32, target)
+
+		plainText, err := parsePayload(PayloadBinary, payload, testHMACKey)
+		a.NoError(err, target)
+		a.Equal(string(tt.text), string(plainText), target)
+
+		_, err = parsePayload(PayloadBinary, payload, testHMACKey+"x")
+		a.EqualError(err, "integrity check failed: HMAC finger print error", target)
+		a.True(errors.Is(err, ErrIntegrity), target)
+
+		// legacy text payload
+		textPayload := fmt.Sprintf("%s.%s", encodeBase64(tt.text), encodeBase64String(HashHMAC(string(tt.text), testHMACKey)))
+		plainText, err = parsePayload(PayloadText, []byte(textPayload), testHMACKey)
+		a.NoError(err, target)
+		a.Equal(string(tt.text), string(plainText), target)
+	}
+
+	_, err := parsePayload(PayloadBinary, []byte("short"), testHMACKey)
+	a.EqualError(err, "cipherText is malformed: payload is too short: size=[5]")
+
+	_, err = parsePayload(2, []byte("short"), testHMACKey)
+	a.EqualError(err, "cipherText is malformed: payload format=[2] is not supported")
+}
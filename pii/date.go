@@ -0,0 +1,41 @@
+package pii
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// DateOfBirthLayout is the canonical form of DateOfBirth.
+const DateOfBirthLayout = "2006-01-02"
+
+var defaultDateOfBirthLayouts = []string{
+	DateOfBirthLayout,
+	"2006/01/02",
+	"20060102",
+	"2006.01.02",
+}
+
+// DateOfBirth normalizes date of birth to `YYYY-MM-DD`.
+type DateOfBirth struct {
+	// Layouts are accepted formats of time.Parse. (e.g. `01/02/2006`)
+	// Year-first formats are used when it's empty, because day-first and month-first are ambiguous.
+	Layouts []string
+}
+
+// Normalize returns canonical form of the date of birth.
+func (d DateOfBirth) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	layouts := d.Layouts
+	if len(layouts) == 0 {
+		layouts = defaultDateOfBirthLayouts
+	}
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t.Format(DateOfBirthLayout), nil
+		}
+	}
+	return "", errors.New("date of birth is invalid")
+}
@@ -0,0 +1,39 @@
+package pii
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateOfBirth(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		layouts    []string
+		value      string
+		expected   string
+		errMessage string
+	}{
+		{nil, "1990-01-02", "1990-01-02", ""},
+		{nil, " 1990/01/02 ", "1990-01-02", ""},
+		{nil, "19900102", "1990-01-02", ""},
+		{nil, "1990.01.02", "1990-01-02", ""},
+		{nil, "01/02/1990", "", "date of birth is invalid"},
+		{nil, "1990-02-30", "", "date of birth is invalid"},
+		{[]string{"01/02/2006"}, "01/02/1990", "1990-01-02", ""},
+		{[]string{"02/01/2006"}, "01/02/1990", "1990-02-01", ""},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		result, err := DateOfBirth{Layouts: tt.layouts}.Normalize(tt.value)
+		if tt.errMessage != "" {
+			a.EqualError(err, tt.errMessage, target)
+			continue
+		}
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+	}
+}
@@ -0,0 +1,34 @@
+package pii
+
+import (
+	"errors"
+	"strings"
+)
+
+// Email normalizes email address.
+// Spaces are trimmed and the address is lower-cased.
+type Email struct {
+	// StripPlusTag removes sub-address from the local part. (e.g. `foo+news@example.com` -> `foo@example.com`)
+	StripPlusTag bool
+}
+
+// Normalize returns canonical form of the email address.
+func (e Email) Normalize(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	at := strings.LastIndex(value, "@")
+	if at <= 0 || at == len(value)-1 {
+		return "", errors.New("email is invalid")
+	}
+	local, domain := value[:at], value[at+1:]
+	if strings.ContainsAny(value, " \t\r\n") {
+		return "", errors.New("email has spaces")
+	}
+
+	if e.StripPlusTag {
+		if i := strings.Index(local, "+"); i > 0 {
+			local = local[:i]
+		}
+	}
+	return local + "@" + strings.TrimSuffix(domain, "."), nil
+}
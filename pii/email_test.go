@@ -0,0 +1,41 @@
+package pii
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmail(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		stripPlusTag bool
+		value        string
+		expected     string
+		errMessage   string
+	}{
+		{false, "foo@example.com", "foo@example.com", ""},
+		{false, " Foo@Example.COM ", "foo@example.com", ""},
+		{false, "foo+news@example.com", "foo+news@example.com", ""},
+		{true, "Foo+News@example.com", "foo@example.com", ""},
+		{true, "+news@example.com", "+news@example.com", ""},
+		{false, "foo@example.com.", "foo@example.com", ""},
+		{false, "foo", "", "email is invalid"},
+		{false, "@example.com", "", "email is invalid"},
+		{false, "foo@", "", "email is invalid"},
+		{false, "foo @example.com", "", "email has spaces"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		result, err := Email{StripPlusTag: tt.stripPlusTag}.Normalize(tt.value)
+		if tt.errMessage != "" {
+			a.EqualError(err, tt.errMessage, target)
+			continue
+		}
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+	}
+}
@@ -0,0 +1,63 @@
+package pii
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// NationalID normalizes national identification number. (e.g. SSN, My Number)
+// Spaces and hyphens are removed, and letters are upper-cased.
+type NationalID struct {
+	// Length is the number of characters after normalization, it's not checked when it's zero.
+	Length int
+	// DigitsOnly rejects letters.
+	DigitsOnly bool
+}
+
+// SSN is NationalID for US Social Security Number. (e.g. `123-45-6789` -> `123456789`)
+var SSN = NationalID{Length: 9, DigitsOnly: true}
+
+// Normalize returns canonical form of the national id.
+func (n NationalID) Normalize(value string) (string, error) {
+	id := removeSeparators(value)
+	switch {
+	case id == "":
+		return "", errors.New("national id is empty")
+	case n.Length != 0 && len(id) != n.Length:
+		return "", fmt.Errorf("national id is invalid: size=[%d]", len(id))
+	case n.DigitsOnly && !isDigits(id),
+		!isAlphanumeric(id):
+		return "", errors.New("national id has invalid character")
+	}
+	return id, nil
+}
+
+// PostalCode normalizes postal code. (e.g. `sw1a 1aa` -> `SW1A1AA`, `100-0001` -> `1000001`)
+// Spaces and hyphens are removed, and letters are upper-cased.
+type PostalCode struct{}
+
+const (
+	postalCodeMinSize = 3
+	postalCodeMaxSize = 10
+)
+
+// Normalize returns canonical form of the postal code.
+func (PostalCode) Normalize(value string) (string, error) {
+	code := removeSeparators(value)
+	switch {
+	case len(code) < postalCodeMinSize || len(code) > postalCodeMaxSize:
+		return "", fmt.Errorf("postal code is invalid: size=[%d]", len(code))
+	case !isAlphanumeric(code):
+		return "", errors.New("postal code has invalid character")
+	}
+	return code, nil
+}
+
+// removeSeparators removes spaces and hyphens, and returns upper-cased value.
+func removeSeparators(value string) string {
+	return strings.NewReplacer(
+		" ", "",
+		"-", "",
+	).Replace(strings.ToUpper(strings.TrimSpace(value)))
+}
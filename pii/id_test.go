@@ -0,0 +1,67 @@
+package pii
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNationalID(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		normalizer NationalID
+		value      string
+		expected   string
+		errMessage string
+	}{
+		{SSN, "123-45-6789", "123456789", ""},
+		{SSN, " 123 45 6789 ", "123456789", ""},
+		{SSN, "123456789", "123456789", ""},
+		{SSN, "123-45-678", "", "national id is invalid: size=[8]"},
+		{SSN, "123-45-678A", "", "national id has invalid character"},
+		{SSN, "", "", "national id is empty"},
+		{NationalID{}, "ab-123 456", "AB123456", ""},
+		{NationalID{}, "AB_123", "", "national id has invalid character"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		result, err := tt.normalizer.Normalize(tt.value)
+		if tt.errMessage != "" {
+			a.EqualError(err, tt.errMessage, target)
+			continue
+		}
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+	}
+}
+
+func TestPostalCode(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		value      string
+		expected   string
+		errMessage string
+	}{
+		{"100-0001", "1000001", ""},
+		{"sw1a 1aa", "SW1A1AA", ""},
+		{"94103", "94103", ""},
+		{"12", "", "postal code is invalid: size=[2]"},
+		{"12345678901", "", "postal code is invalid: size=[11]"},
+		{"1000_001", "", "postal code has invalid character"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		result, err := PostalCode{}.Normalize(tt.value)
+		if tt.errMessage != "" {
+			a.EqualError(err, tt.errMessage, target)
+			continue
+		}
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+	}
+}
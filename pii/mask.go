@@ -0,0 +1,100 @@
+package pii
+
+import (
+	"strings"
+)
+
+// RedactedValue is the masked form of values which don't have Masker.
+const RedactedValue = "[REDACTED]"
+
+const maskChars = "***"
+
+// Masker masks PII for logs and messages. (e.g. `foo@example.com` -> `f***@e***.com`)
+// Normalizers in this package implement Masker.
+type Masker interface {
+	Mask(value string) string
+}
+
+// MaskerFunc is a function which implements Masker.
+type MaskerFunc func(value string) string
+
+// Mask calls f(value).
+func (f MaskerFunc) Mask(value string) string {
+	return f(value)
+}
+
+// Mask masks value by m.
+// RedactedValue is returned when m is nil.
+func Mask(m Masker, value string) string {
+	if m == nil {
+		return RedactedValue
+	}
+	return m.Mask(value)
+}
+
+// Mask keeps the first characters of the local part and the domain, and the top level domain.
+// (e.g. `foo@example.com` -> `f***@e***.com`)
+func (Email) Mask(value string) string {
+	value = strings.TrimSpace(value)
+	at := strings.LastIndex(value, "@")
+	if at <= 0 {
+		return maskChars
+	}
+
+	local, domain := value[:at], value[at+1:]
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return firstChar(local) + maskChars + "@" + maskChars
+	}
+	return firstChar(local) + maskChars + "@" + firstChar(labels[0]) + maskChars + "." + labels[len(labels)-1]
+}
+
+// Mask keeps the last 4 digits. (e.g. `+819012345678` -> `***5678`)
+func (Phone) Mask(value string) string {
+	return maskLast(value, func(r rune) bool {
+		return r >= '0' && r <= '9'
+	})
+}
+
+// Mask keeps the last 4 characters. (e.g. `123-45-6789` -> `***6789`)
+func (NationalID) Mask(value string) string {
+	return maskLast(removeSeparators(value), func(r rune) bool {
+		return true
+	})
+}
+
+// Mask keeps the first 2 characters. (e.g. `100-0001` -> `10***`)
+func (PostalCode) Mask(value string) string {
+	code := []rune(removeSeparators(value))
+	if len(code) <= 2 {
+		return maskChars
+	}
+	return string(code[:2]) + maskChars
+}
+
+// Mask hides the whole date.
+func (DateOfBirth) Mask(value string) string {
+	return maskChars
+}
+
+// firstChar returns the first character of s.
+func firstChar(s string) string {
+	for _, r := range s {
+		return string(r)
+	}
+	return ""
+}
+
+// maskLast keeps the last 4 characters which satisfy fn.
+func maskLast(value string, fn func(rune) bool) string {
+	var chars []rune
+	for _, r := range value {
+		if fn(r) {
+			chars = append(chars, r)
+		}
+	}
+	if len(chars) <= 4 {
+		return maskChars
+	}
+	return maskChars + string(chars[len(chars)-4:])
+}
@@ -0,0 +1,39 @@
+package pii
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMask(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		masker   Masker
+		value    string
+		expected string
+	}{
+		{nil, "foo@example.com", "[REDACTED]"},
+		{Email{}, "foo@example.com", "f***@e***.com"},
+		{Email{}, "foo@mail.example.co.jp", "f***@m***.jp"},
+		{Email{}, "あいう@example.com", "あ***@e***.com"},
+		{Email{}, "foo@localhost", "f***@***"},
+		{Email{}, "foo", "***"},
+		{Phone{}, "+81 90-1234-5678", "***5678"},
+		{Phone{}, "5678", "***"},
+		{SSN, "123-45-6789", "***6789"},
+		{PostalCode{}, "100-0001", "10***"},
+		{PostalCode{}, "10", "***"},
+		{DateOfBirth{}, "1990-01-02", "***"},
+		{MaskerFunc(func(v string) string {
+			return v[:1] + "***"
+		}), "foo", "f***"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, Mask(tt.masker, tt.value), target)
+	}
+}
@@ -0,0 +1,44 @@
+package pii
+
+// Normalizer normalizes PII to the canonical form before indexing and encryption,
+// so that equal values produce the same blind index token.
+type Normalizer interface {
+	Normalize(value string) (string, error)
+}
+
+// NormalizerFunc is a function which implements Normalizer.
+type NormalizerFunc func(value string) (string, error)
+
+// Normalize calls f(value).
+func (f NormalizerFunc) Normalize(value string) (string, error) {
+	return f(value)
+}
+
+// Normalize normalizes value by n.
+// value is returned as is when n is nil.
+func Normalize(n Normalizer, value string) (string, error) {
+	if n == nil {
+		return value, nil
+	}
+	return n.Normalize(value)
+}
+
+// isDigits reports whether s consists of ASCII digits.
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlphanumeric reports whether s consists of ASCII digits and upper case letters.
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package pii
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		normalizer Normalizer
+		value      string
+		expected   string
+	}{
+		{nil, " Foo ", " Foo "},
+		{Email{}, " Foo@Example.com ", "foo@example.com"},
+		{NormalizerFunc(func(v string) (string, error) {
+			return strings.TrimSpace(v), nil
+		}), " Foo ", "Foo"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		result, err := Normalize(tt.normalizer, tt.value)
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+	}
+}
@@ -0,0 +1,50 @@
+package pii
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	phoneMinDigits = 8
+	phoneMaxDigits = 15 // E.164
+)
+
+// Phone normalizes phone number to E.164 format. (e.g. `+819012345678`)
+// Spaces, hyphens, dots and parentheses are removed.
+type Phone struct {
+	// DefaultCountryCode is used when the number doesn't have a country code. (e.g. `1`, `81`)
+	// The trunk prefix `0` of the national number is removed.
+	DefaultCountryCode string
+}
+
+// Normalize returns E.164 form of the phone number.
+func (p Phone) Normalize(value string) (string, error) {
+	number := strings.NewReplacer(
+		" ", "",
+		"-", "",
+		".", "",
+		"(", "",
+		")", "",
+	).Replace(strings.TrimSpace(value))
+
+	switch {
+	case strings.HasPrefix(number, "+"):
+		number = number[1:]
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case p.DefaultCountryCode != "":
+		number = strings.TrimPrefix(p.DefaultCountryCode, "+") + strings.TrimPrefix(number, "0")
+	default:
+		return "", errors.New("phone number does not have country code")
+	}
+
+	if !isDigits(number) {
+		return "", errors.New("phone number has invalid character")
+	}
+	if len(number) < phoneMinDigits || len(number) > phoneMaxDigits {
+		return "", fmt.Errorf("phone number is invalid: digits=[%d]", len(number))
+	}
+	return "+" + number, nil
+}
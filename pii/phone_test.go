@@ -0,0 +1,41 @@
+package pii
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhone(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		countryCode string
+		value       string
+		expected    string
+		errMessage  string
+	}{
+		{"", "+81 90-1234-5678", "+819012345678", ""},
+		{"", "+1 (415) 555.2671", "+14155552671", ""},
+		{"", "0081 90 1234 5678", "+819012345678", ""},
+		{"81", "090-1234-5678", "+819012345678", ""},
+		{"+1", "(415) 555-2671", "+14155552671", ""},
+		{"1", "+81 90-1234-5678", "+819012345678", ""},
+		{"", "090-1234-5678", "", "phone number does not have country code"},
+		{"", "+81 90-1234-567a", "", "phone number has invalid character"},
+		{"", "+81 1234", "", "phone number is invalid: digits=[6]"},
+		{"", "+81 9012345678901234", "", "phone number is invalid: digits=[18]"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		result, err := Phone{DefaultCountryCode: tt.countryCode}.Normalize(tt.value)
+		if tt.errMessage != "" {
+			a.EqualError(err, tt.errMessage, target)
+			continue
+		}
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+	}
+}
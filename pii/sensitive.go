@@ -0,0 +1,72 @@
+package pii
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Sensitive holds PII, which is printed as masked form by fmt, encoding/json and log/slog.
+// The raw value is returned only by Reveal.
+// It's kept behind a pointer, because fmt prints unexported fields of the enclosing struct without calling Format.
+type Sensitive[T any] struct {
+	value  *T
+	masker Masker
+}
+
+// PII is Sensitive string.
+type PII = Sensitive[string]
+
+// NewSensitive creates Sensitive masked by m.
+// RedactedValue is printed when m is nil.
+func NewSensitive[T any](value T, m Masker) Sensitive[T] {
+	return Sensitive[T]{
+		value:  &value,
+		masker: m,
+	}
+}
+
+// Reveal returns the raw value.
+func (s Sensitive[T]) Reveal() T {
+	if s.value == nil {
+		var zero T
+		return zero
+	}
+	return *s.value
+}
+
+// String returns masked value.
+func (s Sensitive[T]) String() string {
+	if s.masker == nil {
+		return RedactedValue
+	}
+	return s.masker.Mask(fmt.Sprint(s.Reveal()))
+}
+
+// GoString returns masked value for `%#v`.
+func (s Sensitive[T]) GoString() string {
+	return fmt.Sprintf("pii.Sensitive{%q}", s.String())
+}
+
+// Format implements fmt.Formatter, and prints masked value for all verbs.
+func (s Sensitive[T]) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('#'):
+		_, _ = io.WriteString(f, s.GoString())
+	case verb == 'q':
+		_, _ = fmt.Fprintf(f, "%q", s.String())
+	default:
+		_, _ = io.WriteString(f, s.String())
+	}
+}
+
+// MarshalJSON returns masked value as JSON string.
+func (s Sensitive[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// LogValue implements slog.LogValuer, and returns masked value.
+func (s Sensitive[T]) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
@@ -0,0 +1,66 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensitive(t *testing.T) {
+	a := assert.New(t)
+
+	email := NewSensitive("foo@example.com", Email{})
+	a.Equal("foo@example.com", email.Reveal())
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"%s", "f***@e***.com"},
+		{"%v", "f***@e***.com"},
+		{"%+v", "f***@e***.com"},
+		{"%#v", `pii.Sensitive{"f***@e***.com"}`},
+		{"%q", `"f***@e***.com"`},
+		{"%x", "f***@e***.com"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, fmt.Sprintf(tt.format, email), target)
+	}
+
+	// nested
+	user := struct {
+		Email PII
+	}{email}
+	a.Equal("{Email:f***@e***.com}", fmt.Sprintf("%+v", user))
+
+	// unexported field is printed without Format.
+	private := struct {
+		email PII
+	}{email}
+	for _, format := range []string{"%v", "%+v", "%#v"} {
+		a.NotContains(fmt.Sprintf(format, private), "foo@example.com", format)
+	}
+
+	byt, err := json.Marshal(user)
+	a.NoError(err)
+	a.Equal(`{"Email":"f***@e***.com"}`, string(byt))
+
+	// slog
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("login", "email", email)
+	a.Contains(buf.String(), "email=f***@e***.com")
+	a.NotContains(buf.String(), "foo@example.com")
+
+	// without Masker
+	age := NewSensitive(20, nil)
+	a.Equal(20, age.Reveal())
+	a.Equal("[REDACTED]", fmt.Sprintf("%v", age))
+	a.Equal("[REDACTED]", Sensitive[string]{}.String())
+	a.Equal("", Sensitive[string]{}.Reveal())
+}
@@ -0,0 +1,137 @@
+package hierogolyph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	personalKeyEntropySize  = 15 // 120bit, 24 characters.
+	personalKeyChecksumSize = 4  // 20bit
+	personalKeyGroupSize    = 4
+)
+
+// personalKeyEncoding is Crockford's Base32, which doesn't have confusing letters (I, L, O, U).
+var personalKeyEncoding = base32.NewEncoding("0123456789ABCDEFGHJKMNPQRSTVWXYZ").WithPadding(base32.NoPadding)
+
+// GeneratePersonalKey generates a personal key, which is human-typeable code with checksum.
+// (e.g. `0A1B-2C3D-4E5F-6G7H-8J9K-AMBN-CPDQ`)
+func GeneratePersonalKey() (string, error) {
+	byt, err := getRandomBytes(personalKeyEntropySize)
+	if err != nil {
+		return "", err
+	}
+
+	code := personalKeyEncoding.EncodeToString(byt)
+	code += personalKeyChecksum(byt)
+
+	groups := make([]string, 0, len(code)/personalKeyGroupSize)
+	for i := 0; i < len(code); i += personalKeyGroupSize {
+		groups = append(groups, code[i:i+personalKeyGroupSize])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// NormalizePersonalKey validates the personal key and returns canonical form without separators.
+// Lower case, spaces, hyphens and confusing letters (I, L, O) are accepted.
+func NormalizePersonalKey(code string) (string, error) {
+	code = strings.NewReplacer(
+		"-", "",
+		" ", "",
+		"I", "1",
+		"L", "1",
+		"O", "0",
+	).Replace(strings.ToUpper(code))
+
+	size := personalKeyEncoding.EncodedLen(personalKeyEntropySize)
+	if len(code) != size+personalKeyChecksumSize {
+		return "", fmt.Errorf("personal key is invalid: size=[%d]", len(code))
+	}
+	byt, err := personalKeyEncoding.DecodeString(code[:size])
+	if err != nil {
+		return "", errors.New("personal key has invalid character")
+	}
+	if code[size:] != personalKeyChecksum(byt) {
+		return "", errors.New("personal key checksum is not matched")
+	}
+	return code, nil
+}
+
+// personalKeyChecksum returns checksum characters of the personal key.
+func personalKeyChecksum(byt []byte) string {
+	sum := sha256.Sum256(byt)
+	return personalKeyEncoding.EncodeToString(sum[:])[:personalKeyChecksumSize]
+}
+
+// CreatePersonalKey generates a personal key and sets RecoveryKey, which wraps the same key as EncryptionKey.
+// The personal key is shown to the user only once, and RecoveryKey must be saved.
+func (h *Hierogolyph) CreatePersonalKey() (string, error) {
+	cek, err := h.unlock(context.Background())
+	if err != nil {
+		return "", err
+	}
+	defer zeroBytes(cek)
+
+	code, err := GeneratePersonalKey()
+	if err != nil {
+		return "", err
+	}
+	normalized, err := NormalizePersonalKey(code)
+	if err != nil {
+		return "", err
+	}
+	salt, err := getRandomString(20)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := h.Config.newEncryptionKeyV1(normalized, salt, cek)
+	if err != nil {
+		return "", err
+	}
+	key.Salt = salt
+
+	h.RecoveryKey = key.String()
+	return code, nil
+}
+
+// RecoverWithPersonalKey resets the password by the personal key without losing encrypted data.
+// Salt and EncryptionKey are changed, so they must be saved again.
+// RecoveryKey is not changed, call CreatePersonalKey to issue a new personal key.
+func (h *Hierogolyph) RecoverWithPersonalKey(code, newPassword string) error {
+	normalized, err := NormalizePersonalKey(code)
+	if err != nil {
+		return err
+	}
+	key, err := parseEncryptionKey(h.RecoveryKey)
+	if err != nil {
+		return newError(ErrWrongKey, err)
+	}
+	if key.Version != EncryptionKeyVersion1 || key.Salt == "" {
+		return newError(ErrWrongKey, errors.New("recoveryKey is invalid"))
+	}
+
+	secret, _, err := h.Config.unwrapKey(context.Background(), key, normalized, key.Salt)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(secret)
+
+	salt, err := getRandomString(20)
+	if err != nil {
+		return err
+	}
+	ek, err := h.Config.createEncryptionKeyV1(newPassword, salt, secret)
+	if err != nil {
+		return err
+	}
+
+	h.Password = newPassword
+	h.Salt = salt
+	h.EncryptionKey = ek
+	return nil
+}
@@ -0,0 +1,162 @@
+package hierogolyph
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	hsmchacha "github.com/evalphobia/hierogolyph/hsm/chacha20poly1305"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratePersonalKey(t *testing.T) {
+	a := assert.New(t)
+	format := regexp.MustCompile(`^([0-9A-HJKMNP-TV-Z]{4}-){6}[0-9A-HJKMNP-TV-Z]{4}$`)
+
+	codes := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := GeneratePersonalKey()
+		a.NoError(err)
+		a.Regexp(format, code)
+		a.False(codes[code])
+		codes[code] = true
+
+		normalized, err := NormalizePersonalKey(code)
+		a.NoError(err)
+		a.Equal(strings.Replace(code, "-", "", -1), normalized)
+	}
+}
+
+func TestNormalizePersonalKey(t *testing.T) {
+	a := assert.New(t)
+
+	const valid = "0A1B-2C3D-4E5F-6G7H-8J9K-AMBN"
+	code := valid + "-" + personalKeyChecksum(mustDecodePersonalKey(strings.Replace(valid, "-", "", -1)))
+	normalized := strings.Replace(code, "-", "", -1)
+
+	tests := []struct {
+		code       string
+		expected   string
+		errMessage string
+	}{
+		{code, normalized, ""},
+		{strings.ToLower(code), normalized, ""},
+		{strings.Replace(code, "-", " ", -1), normalized, ""},
+		{strings.Replace(strings.Replace(code, "0", "o", -1), "1", "l", -1), normalized, ""},
+		{strings.Replace(code, "1", "I", -1), normalized, ""},
+		{"", "", "personal key is invalid: size=[0]"},
+		{code + "0", "", "personal key is invalid: size=[29]"},
+		{"U" + normalized[1:], "", "personal key has invalid character"},
+		{"1" + normalized[1:], "", "personal key checksum is not matched"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		result, err := NormalizePersonalKey(tt.code)
+		if tt.errMessage != "" {
+			a.EqualError(err, tt.errMessage, target)
+			continue
+		}
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+	}
+}
+
+func TestHierogolyph_RecoverWithPersonalKey(t *testing.T) {
+	a := assert.New(t)
+
+	legacy := testHierogolyph1
+	legacy.Config = testConfig
+	created, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+
+	for _, h := range []Hierogolyph{legacy, created} {
+		target := h.EncryptionKey
+		cipherText, err := h.Encrypt("plain text")
+		a.NoError(err, target)
+
+		code, err := h.CreatePersonalKey()
+		a.NoError(err, target)
+		a.NotEmpty(h.RecoveryKey, target)
+
+		// wrong personal key
+		other, err := GeneratePersonalKey()
+		a.NoError(err, target)
+		lost := Hierogolyph{Config: testConfig, RecoveryKey: h.RecoveryKey}
+		err = lost.RecoverWithPersonalKey(other, "new password")
+		a.True(errors.Is(err, ErrWrongKey), target)
+		a.Empty(lost.EncryptionKey, target)
+
+		// the password is lost.
+		a.NoError(lost.RecoverWithPersonalKey(strings.ToLower(code), "new password"), target)
+		a.Equal("new password", lost.Password, target)
+		a.NotEmpty(lost.Salt, target)
+
+		plainText, err := lost.Decrypt(cipherText)
+		a.NoError(err, target)
+		a.Equal("plain text", plainText, target)
+		ok, err := lost.VerifyPassword("new password")
+		a.NoError(err, target)
+		a.True(ok, target)
+	}
+
+	// RecoveryKey is not created.
+	h := created
+	h.RecoveryKey = ""
+	code, err := GeneratePersonalKey()
+	a.NoError(err)
+	err = h.RecoverWithPersonalKey(code, "new password")
+	a.True(errors.Is(err, ErrWrongKey))
+	err = h.RecoverWithPersonalKey("invalid", "new password")
+	a.EqualError(err, "personal key is invalid: size=[7]")
+}
+
+func TestHierogolyph_RecoverAfterRotateHSM(t *testing.T) {
+	a := assert.New(t)
+	newHSM := hsmchacha.NewMockHSM([]byte("12345678901234567890123456789012"))
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	cipherText, err := h.Encrypt("plain text")
+	a.NoError(err)
+	code, err := h.CreatePersonalKey()
+	a.NoError(err)
+	oldRecoveryKey := h.RecoveryKey
+
+	a.NoError(h.RotateHSM(newHSM))
+	a.NotEqual(oldRecoveryKey, h.RecoveryKey)
+	newConf := testConfig
+	newConf.HSM = newHSM
+	newConf.Registry = NewEmptyRegistry()
+
+	// RecoveryKey is wrapped by the new HSM.
+	lost := Hierogolyph{Config: newConf, RecoveryKey: h.RecoveryKey}
+	a.NoError(lost.RecoverWithPersonalKey(code, "new password"))
+	cek, err := lost.Unlock()
+	a.NoError(err)
+	expected, err := h.Unlock()
+	a.NoError(err)
+	a.Equal(expected, cek)
+
+	// RecoveryKey which is not rotated is unwrapped by the old HSM in the registry.
+	lost = Hierogolyph{Config: newConf, RecoveryKey: oldRecoveryKey}
+	err = lost.RecoverWithPersonalKey(code, "new password")
+	a.EqualError(err, "hsm is not registered: name=[mock-aes-gcm]")
+	lost.Config.Registry = NewEmptyRegistry()
+	a.NoError(lost.Config.Registry.RegisterHSM(testConfig.HSM))
+	a.NoError(lost.RecoverWithPersonalKey(code, "new password"))
+	plainText, err := lost.Decrypt(cipherText)
+	a.NoError(err)
+	a.Equal("plain text", plainText)
+}
+
+func mustDecodePersonalKey(code string) []byte {
+	byt, err := personalKeyEncoding.DecodeString(code)
+	if err != nil {
+		panic(err)
+	}
+	return byt
+}
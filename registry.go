@@ -0,0 +1,142 @@
+package hierogolyph
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/evalphobia/hierogolyph/cipher"
+	"github.com/evalphobia/hierogolyph/cipher/aesgcm"
+	"github.com/evalphobia/hierogolyph/cipher/aessiv"
+	"github.com/evalphobia/hierogolyph/cipher/chacha20poly1305"
+	"github.com/evalphobia/hierogolyph/hasher"
+	"github.com/evalphobia/hierogolyph/hasher/argon2"
+	"github.com/evalphobia/hierogolyph/hasher/pbkdf2"
+	"github.com/evalphobia/hierogolyph/hasher/scrypt"
+	"github.com/evalphobia/hierogolyph/hsm"
+)
+
+// DefaultRegistry is used when Config.Registry is nil.
+// It has built-in ciphers and hashers, HSM must be registered by yourself.
+// Balloon is not registered to avoid its dependency, register it by yourself when it's used.
+// (e.g. `DefaultRegistry.RegisterHasher(balloon.Balloon{})`)
+var DefaultRegistry = NewRegistry()
+
+// Registry resolves Cipher, Hasher and HSM from algorithm names recorded in the envelope.
+type Registry struct {
+	mu      sync.RWMutex
+	ciphers map[string]cipher.Cipher
+	hashers map[string]hasher.Hasher
+	hsms    map[string]hsm.HSM
+}
+
+// NewRegistry creates Registry with built-in ciphers and hashers.
+func NewRegistry() *Registry {
+	r := NewEmptyRegistry()
+	_ = r.RegisterCipher(aesgcm.Cipher{})
+	_ = r.RegisterCipher(chacha20poly1305.Cipher{})
+	_ = r.RegisterCipher(aessiv.Cipher{})
+	_ = r.RegisterHasher(argon2.Argon2{})
+	_ = r.RegisterHasher(pbkdf2.PBKDF2{})
+	_ = r.RegisterHasher(scrypt.SCrypt{})
+	return r
+}
+
+// NewEmptyRegistry creates Registry without any algorithm.
+func NewEmptyRegistry() *Registry {
+	return &Registry{
+		ciphers: make(map[string]cipher.Cipher),
+		hashers: make(map[string]hasher.Hasher),
+		hsms:    make(map[string]hsm.HSM),
+	}
+}
+
+// RegisterCipher registers Cipher, which must implement cipher.Algorithm.
+func (r *Registry) RegisterCipher(c cipher.Cipher) error {
+	v, ok := c.(cipher.Algorithm)
+	if !ok {
+		return fmt.Errorf("cipher must implement cipher.Algorithm: type=[%T]", c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ciphers[v.Algorithm()] = c
+	return nil
+}
+
+// RegisterHasher registers Hasher, which must implement hasher.Algorithm.
+// Hasher which implements hasher.Validator must be valid, so that the name identifies it.
+// When the Hasher implements hasher.Restorer, it's restored from cost parameters in the envelope.
+// The built-in hashers reject the parameters exceeding the default maximum,
+// register a Hasher with higher cost to raise the maximum.
+// Otherwise, cost parameters in the envelope must be the same as the registered one.
+func (r *Registry) RegisterHasher(h hasher.Hasher) error {
+	v, ok := h.(hasher.Algorithm)
+	if !ok {
+		return fmt.Errorf("hasher must implement hasher.Algorithm: type=[%T]", h)
+	}
+	if vv, ok := h.(hasher.Validator); ok {
+		if err := vv.Validate(); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hashers[v.Algorithm()] = h
+	return nil
+}
+
+// RegisterHSM registers HSM, which must implement hsm.Provider.
+func (r *Registry) RegisterHSM(h hsm.HSM) error {
+	v, ok := h.(hsm.Provider)
+	if !ok {
+		return fmt.Errorf("hsm must implement hsm.Provider: type=[%T]", h)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hsms[v.Provider()] = h
+	return nil
+}
+
+// Cipher returns registered Cipher.
+func (r *Registry) Cipher(name string) (cipher.Cipher, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.ciphers[name]
+	if !ok {
+		return nil, fmt.Errorf("cipher is not registered: name=[%s]", name)
+	}
+	return c, nil
+}
+
+// Hasher returns registered Hasher which has given cost parameters.
+func (r *Registry) Hasher(name, params string) (hasher.Hasher, error) {
+	r.mu.RLock()
+	h, ok := r.hashers[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("hasher is not registered: name=[%s]", name)
+	}
+
+	if v, ok := h.(hasher.Restorer); ok {
+		return v.WithParams(params)
+	}
+	if h.(hasher.Algorithm).Params() != params {
+		return nil, fmt.Errorf("hasher is registered with different params: name=[%s], params=[%s]", name, params)
+	}
+	return h, nil
+}
+
+// HSM returns registered HSM.
+func (r *Registry) HSM(name string) (hsm.HSM, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	h, ok := r.hsms[name]
+	if !ok {
+		return nil, fmt.Errorf("hsm is not registered: name=[%s]", name)
+	}
+	return h, nil
+}
@@ -0,0 +1,163 @@
+package hierogolyph
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/cipher/aesgcm"
+	"github.com/evalphobia/hierogolyph/cipher/chacha20poly1305"
+	"github.com/evalphobia/hierogolyph/hasher"
+	"github.com/evalphobia/hierogolyph/hasher/argon2"
+	"github.com/evalphobia/hierogolyph/hasher/pbkdf2"
+	"github.com/evalphobia/hierogolyph/hasher/scrypt"
+	hsmgcm "github.com/evalphobia/hierogolyph/hsm/aesgcm"
+	hsmchacha "github.com/evalphobia/hierogolyph/hsm/chacha20poly1305"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUnnamedCipher struct{ aesgcm.Cipher }
+
+func (testUnnamedCipher) Algorithm() {}
+
+type testUnnamedHasher struct{}
+
+func (testUnnamedHasher) Hash(password, salt string) string { return "" }
+
+type testUnnamedHSM struct{ *hsmgcm.MockHSM }
+
+func (testUnnamedHSM) Provider() {}
+
+func TestRegistry(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewEmptyRegistry()
+	a.EqualError(r.RegisterCipher(testUnnamedCipher{}), "cipher must implement cipher.Algorithm: type=[hierogolyph.testUnnamedCipher]")
+	a.EqualError(r.RegisterHasher(testUnnamedHasher{}), "hasher must implement hasher.Algorithm: type=[hierogolyph.testUnnamedHasher]")
+	a.EqualError(r.RegisterHSM(testUnnamedHSM{}), "hsm must implement hsm.Provider: type=[hierogolyph.testUnnamedHSM]")
+
+	_, err := r.Cipher("aes-256-gcm")
+	a.EqualError(err, "cipher is not registered: name=[aes-256-gcm]")
+	_, err = r.Hasher("argon2id", "")
+	a.EqualError(err, "hasher is not registered: name=[argon2id]")
+	_, err = r.HSM("mock-aes-gcm")
+	a.EqualError(err, "hsm is not registered: name=[mock-aes-gcm]")
+
+	a.NoError(r.RegisterCipher(chacha20poly1305.Cipher{}))
+	a.NoError(r.RegisterHasher(scrypt.SCrypt{}))
+	a.NoError(r.RegisterHSM(hsmchacha.NewMockHSM([]byte(testGCMKey256))))
+
+	c, err := r.Cipher("xchacha20-poly1305")
+	a.NoError(err)
+	a.Equal(chacha20poly1305.Cipher{}, c)
+
+	h, err := r.Hasher("scrypt", "n=1024,r=8,p=1,l=32")
+	a.NoError(err)
+	a.Equal(scrypt.SCrypt{Cost: 1024, BlockSize: 8, Parallelism: 1, KeyLength: 32}, h)
+
+	_, err = r.Hasher("scrypt", "n=1024,r")
+	a.EqualError(err, "params=[n=1024,r] is invalid format")
+	_, err = r.Hasher("scrypt", "n=2147483647,r=8,p=1,l=32")
+	a.EqualError(err, "param value is too large: key=[n], value=[2147483647], max=[1048576]")
+
+	hsm, err := r.HSM("mock-xchacha20-poly1305")
+	a.NoError(err)
+	a.Equal(hsmchacha.NewMockHSM([]byte(testGCMKey256)), hsm)
+}
+
+func TestNewRegistry(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewRegistry()
+	for _, name := range []string{"aes-256-gcm", "xchacha20-poly1305", "aes-256-siv"} {
+		_, err := r.Cipher(name)
+		a.NoError(err, name)
+	}
+	for _, name := range []string{"argon2id", "pbkdf2-sha512", "scrypt"} {
+		_, err := r.Hasher(name, "")
+		a.NoError(err, name)
+	}
+
+	// balloon is registered by yourself.
+	_, err := r.Hasher("balloon-sha512", "")
+	a.EqualError(err, "hasher is not registered: name=[balloon-sha512]")
+}
+
+func TestRegistry_HashFn(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewRegistry()
+	a.EqualError(r.RegisterHasher(pbkdf2.PBKDF2{HashFn: func() hash.Hash { return fnv.New128() }}), "pbkdf2: hash function is unknown")
+	a.NoError(r.RegisterHasher(pbkdf2.PBKDF2{HashFn: sha256.New}))
+
+	// hash function is restored by the name.
+	for _, name := range []string{"pbkdf2-sha512", "pbkdf2-sha256"} {
+		h, err := r.Hasher(name, "i=4096,l=32")
+		a.NoError(err, name)
+		a.Equal(name, h.(hasher.Algorithm).Algorithm(), name)
+	}
+}
+
+func TestHierogolyph_DecryptWithRegistry(t *testing.T) {
+	a := assert.New(t)
+
+	oldConf := testConfig
+	newConf := Config{
+		Cipher:  chacha20poly1305.Cipher{},
+		HSM:     hsmchacha.NewMockHSM([]byte(testGCMKey256)),
+		Hasher:  scrypt.SCrypt{},
+		HMACKey: testHMACKey,
+	}
+
+	h, err := CreateHierogolyph("password", oldConf)
+	a.NoError(err)
+	oldCipherText, err := h.Encrypt("old text")
+	a.NoError(err)
+
+	h2, err := CreateHierogolyph("password", newConf)
+	a.NoError(err)
+	h2.Salt = h.Salt
+	a.NoError(h2.SetEncryptionKey())
+	newCipherText, err := h2.Encrypt("new text")
+	a.NoError(err)
+
+	r := NewRegistry()
+	a.NoError(r.RegisterHSM(oldConf.HSM))
+	h2.Config.Registry = r
+
+	// both of old and new algorithms can be decrypted.
+	plainText, err := h2.Decrypt(oldCipherText)
+	a.NoError(err)
+	a.Equal("old text", plainText)
+
+	plainText, err = h2.Decrypt(newCipherText)
+	a.NoError(err)
+	a.Equal("new text", plainText)
+
+	tests := []struct {
+		errMessage string
+		registry   *Registry
+	}{
+		{"hsm is not registered: name=[mock-aes-gcm]", nil},
+		{"cipher is not registered: name=[aes-256-gcm]", NewEmptyRegistry()},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		h3 := h2
+		h3.Config.Registry = tt.registry
+		_, err := h3.Decrypt(oldCipherText)
+		a.EqualError(err, tt.errMessage, target)
+	}
+
+	// hasher with different params is restored.
+	h4 := h
+	h4.Config.Hasher = argon2.Argon2{Time: 2}
+	plainText, err = h4.Decrypt(oldCipherText)
+	a.NoError(err)
+	a.Equal("old text", plainText)
+}
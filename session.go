@@ -0,0 +1,196 @@
+package hierogolyph
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"sync"
+
+	"github.com/evalphobia/hierogolyph/pii"
+)
+
+// Session is an unlocked Hierogolyph, which holds Content Encryption Key in memory.
+// Encrypt and Decrypt don't call Hasher and HSM, so it's suitable for encrypting many values of the same user.
+// Close must be called to zero the key after use.
+type Session struct {
+	mu            sync.RWMutex
+	conf          Config
+	encryptionKey string
+	keyID         []byte // nil when EncryptionKey is legacy version.
+	cek           []byte
+}
+
+// Open unlocks Hierogolyph and returns Session.
+func (h Hierogolyph) Open() (*Session, error) {
+	return h.OpenContext(context.Background())
+}
+
+// OpenContext unlocks Hierogolyph with ctx and returns Session.
+func (h Hierogolyph) OpenContext(ctx context.Context) (*Session, error) {
+	cek, err := h.unlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newSession(h.Config, h.EncryptionKey, cek), nil
+}
+
+// newSession creates Session.
+func newSession(conf Config, encryptionKey string, cek []byte) *Session {
+	key, _ := parseEncryptionKey(encryptionKey)
+	return &Session{
+		conf:          conf,
+		encryptionKey: encryptionKey,
+		keyID:         key.KeyID,
+		cek:           cek,
+	}
+}
+
+// Close zeroes Content Encryption Key, which is kept as byte slice from unwrapping by HSM.
+// Copies made inside HSM which implements only string interface (not hsm.ByteHSM) are not wiped.
+// The session cannot be used after Close.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	zeroBytes(s.cek)
+	s.cek = nil
+	return nil
+}
+
+// Encrypt encrypts given plainText.
+// The result is the same format as Hierogolyph.Encrypt.
+func (s *Session) Encrypt(plainText string) (cipherText string, err error) {
+	byt, err := s.encrypt([]byte(plainText), nil)
+	if err != nil {
+		return "", err
+	}
+	return string(byt), nil
+}
+
+// EncryptBytes encrypts given plainText.
+func (s *Session) EncryptBytes(plainText []byte) (cipherText []byte, err error) {
+	return s.encrypt(plainText, nil)
+}
+
+// EncryptWithAAD encrypts given plainText with additional authenticated data.
+func (s *Session) EncryptWithAAD(plainText string, aad []byte) (cipherText string, err error) {
+	if len(aad) == 0 {
+		return "", newError(ErrInvalidConfig, errEmptyAAD)
+	}
+
+	byt, err := s.encrypt([]byte(plainText), aad)
+	if err != nil {
+		return "", err
+	}
+	return string(byt), nil
+}
+
+// EncryptNormalized encrypts given plainText after normalization by n.
+func (s *Session) EncryptNormalized(plainText string, n pii.Normalizer) (cipherText string, err error) {
+	normalized, err := pii.Normalize(n, plainText)
+	if err != nil {
+		return "", err
+	}
+	return s.Encrypt(normalized)
+}
+
+// Decrypt decrypts given cipherText.
+// It returns ErrWrongKey when the cipherText is encrypted by another EncryptionKey.
+func (s *Session) Decrypt(cipherText string) (plainText string, err error) {
+	byt, err := s.decrypt([]byte(cipherText), nil)
+	if err != nil {
+		return "", err
+	}
+	return string(byt), nil
+}
+
+// DecryptBytes decrypts given cipherText.
+func (s *Session) DecryptBytes(cipherText []byte) (plainText []byte, err error) {
+	return s.decrypt(cipherText, nil)
+}
+
+// DecryptSensitive decrypts given cipherText, and returns pii.Sensitive which is masked by m in logs.
+func (s *Session) DecryptSensitive(cipherText string, m pii.Masker) (pii.PII, error) {
+	plainText, err := s.Decrypt(cipherText)
+	if err != nil {
+		return pii.PII{}, err
+	}
+	return pii.NewSensitive(plainText, m), nil
+}
+
+// DecryptWithAAD decrypts given cipherText created by EncryptWithAAD.
+func (s *Session) DecryptWithAAD(cipherText string, aad []byte) (plainText string, err error) {
+	if len(aad) == 0 {
+		return "", newError(ErrInvalidConfig, errEmptyAAD)
+	}
+
+	byt, err := s.decrypt([]byte(cipherText), aad)
+	if err != nil {
+		return "", err
+	}
+	return string(byt), nil
+}
+
+// encrypt encrypts plainText by the cached key.
+func (s *Session) encrypt(plainText, aad []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.cek == nil {
+		return nil, errors.New("session is closed")
+	}
+	return encryptWithCEK(s.conf, s.encryptionKey, s.cek, plainText, aad)
+}
+
+// decrypt decrypts cipherText by the cached key.
+func (s *Session) decrypt(cipherText, aad []byte) ([]byte, error) {
+	envelope, err := parseEnvelopeWithAAD(cipherText, aad)
+	if err != nil {
+		return nil, err
+	}
+	if !s.hasKey(envelope) {
+		return nil, newError(ErrWrongKey, errors.New("cipherText is encrypted by another EncryptionKey"))
+	}
+	// Hasher and HSM are not used, because the key is already unlocked.
+	conf, err := s.conf.resolveCipher(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.cek == nil {
+		return nil, errors.New("session is closed")
+	}
+	return decryptWithCEK(conf, envelope, s.cek, aad)
+}
+
+// secret returns a copy of the cached key.
+func (s *Session) secret() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.cek == nil {
+		return nil, errors.New("session is closed")
+	}
+	return append([]byte{}, s.cek...), nil
+}
+
+// hasKey reports whether the envelope can be decrypted by the session key.
+func (s *Session) hasKey(e Envelope) bool {
+	if e.EncryptionKey == s.encryptionKey {
+		return true
+	}
+
+	key, err := parseEncryptionKey(e.EncryptionKey)
+	switch {
+	case err != nil,
+		s.keyID == nil:
+		return false
+	case key.Version == EncryptionKeyVersion1:
+		return hmac.Equal(key.KeyID, s.keyID)
+	}
+	// legacy EncryptionKey can be upgraded to the session key by ChangePassword.
+	return true
+}
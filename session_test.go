@@ -0,0 +1,143 @@
+package hierogolyph
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/cipher/chacha20poly1305"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHierogolyph_Open(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+
+	s, err := h.Open()
+	a.NoError(err)
+
+	// Hasher is not called after Open.
+	count := 0
+	s.conf.Hasher = countHasher{count: &count}
+
+	tests := []struct {
+		text string
+	}{
+		{""},
+		{"a"},
+		{"あいうえお"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		cipherText, err := s.Encrypt(tt.text)
+		a.NoError(err, target)
+
+		// compatible with Hierogolyph.
+		plainText, err := h.Decrypt(cipherText)
+		a.NoError(err, target)
+		a.Equal(tt.text, plainText, target)
+
+		cipherText, err = h.Encrypt(tt.text)
+		a.NoError(err, target)
+		plainText, err = s.Decrypt(cipherText)
+		a.NoError(err, target)
+		a.Equal(tt.text, plainText, target)
+
+		// aad
+		aad := AAD("users", "name", "1")
+		cipherText, err = s.EncryptWithAAD(tt.text, aad)
+		a.NoError(err, target)
+		plainText, err = h.DecryptWithAAD(cipherText, aad)
+		a.NoError(err, target)
+		a.Equal(tt.text, plainText, target)
+		plainText, err = s.DecryptWithAAD(cipherText, aad)
+		a.NoError(err, target)
+		a.Equal(tt.text, plainText, target)
+		_, err = s.DecryptWithAAD(cipherText, AAD("users", "name", "2"))
+		a.True(errors.Is(err, ErrWrongKey), target)
+	}
+	a.Equal(0, count)
+}
+
+func TestSession_Decrypt(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	s, err := h.Open()
+	a.NoError(err)
+
+	// cipher is resolved by the envelope.
+	chacha := h
+	chacha.Config.Cipher = chacha20poly1305.Cipher{}
+	cipherText, err := chacha.Encrypt("plain text")
+	a.NoError(err)
+	plainText, err := s.Decrypt(cipherText)
+	a.NoError(err)
+	a.Equal("plain text", plainText)
+
+	// another EncryptionKey
+	other, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	cipherText, err = other.Encrypt("plain text")
+	a.NoError(err)
+	_, err = s.Decrypt(cipherText)
+	a.True(errors.Is(err, ErrWrongKey))
+
+	// wrong password
+	h.Password = "wrong"
+	_, err = h.Open()
+	a.True(errors.Is(err, ErrWrongKey))
+}
+
+func TestSession_Close(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	s, err := h.Open()
+	a.NoError(err)
+	cipherText, err := s.Encrypt("plain text")
+	a.NoError(err)
+
+	cek := s.cek
+	a.NoError(s.Close())
+	a.Equal(make([]byte, len(cek)), cek)
+	a.NoError(s.Close())
+
+	_, err = s.Encrypt("plain text")
+	a.EqualError(err, "session is closed")
+	_, err = s.Decrypt(cipherText)
+	a.EqualError(err, "session is closed")
+}
+
+func TestSession_Concurrent(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	s, err := h.Open()
+	a.NoError(err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			text := fmt.Sprintf("plain text %d", i)
+			cipherText, err := s.Encrypt(text)
+			a.NoError(err)
+			plainText, err := s.Decrypt(cipherText)
+			a.NoError(err)
+			a.Equal(text, plainText)
+		}(i)
+	}
+	wg.Wait()
+	a.NoError(s.Close())
+}
@@ -0,0 +1,194 @@
+package hierogolyph
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var errNoEncrypter = errors.New("encrypter is not bound")
+
+// EncryptedString is a string column, which is encrypted by driver.Valuer and decrypted by sql.Scanner.
+// Encrypter is bound by NewEncryptedString or Bind, and the default Encrypter is used when it's not bound.
+// Empty string is not encrypted.
+type EncryptedString struct {
+	Plain string
+
+	encrypter Encrypter
+}
+
+// NewEncryptedString creates EncryptedString bound to Encrypter of ctx.
+func NewEncryptedString(ctx context.Context, plainText string) EncryptedString {
+	return EncryptedString{
+		Plain:     plainText,
+		encrypter: EncrypterFromContext(ctx),
+	}
+}
+
+// Bind binds Encrypter, it must be called before Scan when the default Encrypter is not used.
+func (s *EncryptedString) Bind(e Encrypter) {
+	s.encrypter = e
+}
+
+// Value implements driver.Valuer, and returns encrypted value.
+func (s EncryptedString) Value() (driver.Value, error) {
+	if s.Plain == "" {
+		return "", nil
+	}
+	e, err := resolveEncrypter(s.encrypter)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := e.Encrypt(s.Plain)
+	if err != nil {
+		return nil, err
+	}
+	return columnValue(cipherText), nil
+}
+
+// Scan implements sql.Scanner, and decrypts the value.
+func (s *EncryptedString) Scan(src interface{}) error {
+	cipherText, err := scanString(src)
+	if err != nil || cipherText == "" {
+		s.Plain = ""
+		return err
+	}
+	e, err := resolveEncrypter(s.encrypter)
+	if err != nil {
+		return err
+	}
+
+	plainText, err := e.Decrypt(cipherText)
+	if err != nil {
+		return err
+	}
+	s.Plain = plainText
+	return nil
+}
+
+// Encrypted is a column of any type, which is encrypted by driver.Valuer and decrypted by sql.Scanner.
+// string and []byte are encrypted as is, encoding.TextMarshaler is encrypted as text, and others are encrypted as JSON.
+type Encrypted[T any] struct {
+	Plain T
+
+	encrypter Encrypter
+}
+
+// NewEncrypted creates Encrypted bound to Encrypter of ctx.
+func NewEncrypted[T any](ctx context.Context, plain T) Encrypted[T] {
+	return Encrypted[T]{
+		Plain:     plain,
+		encrypter: EncrypterFromContext(ctx),
+	}
+}
+
+// Bind binds Encrypter, it must be called before Scan when the default Encrypter is not used.
+func (v *Encrypted[T]) Bind(e Encrypter) {
+	v.encrypter = e
+}
+
+// Value implements driver.Valuer, and returns encrypted value.
+func (v Encrypted[T]) Value() (driver.Value, error) {
+	e, err := resolveEncrypter(v.encrypter)
+	if err != nil {
+		return nil, err
+	}
+	text, err := marshalPlain(v.Plain)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := e.Encrypt(text)
+	if err != nil {
+		return nil, err
+	}
+	return columnValue(cipherText), nil
+}
+
+// Scan implements sql.Scanner, and decrypts the value.
+func (v *Encrypted[T]) Scan(src interface{}) error {
+	cipherText, err := scanString(src)
+	if err != nil {
+		return err
+	}
+	if cipherText == "" {
+		var zero T
+		v.Plain = zero
+		return nil
+	}
+	e, err := resolveEncrypter(v.encrypter)
+	if err != nil {
+		return err
+	}
+
+	plainText, err := e.Decrypt(cipherText)
+	if err != nil {
+		return err
+	}
+	return unmarshalPlain(plainText, &v.Plain)
+}
+
+// resolveEncrypter returns the bound Encrypter or the default Encrypter.
+func resolveEncrypter(e Encrypter) (Encrypter, error) {
+	if e != nil {
+		return e, nil
+	}
+	if e := EncrypterFromContext(context.Background()); e != nil {
+		return e, nil
+	}
+	return nil, errNoEncrypter
+}
+
+// columnValue returns cipherText as the column value.
+// EncodingBinary is returned as []byte for BYTEA/BLOB columns.
+func columnValue(cipherText string) driver.Value {
+	if detectEncoding(cipherText) == EncodingBinary {
+		return []byte(cipherText)
+	}
+	return cipherText
+}
+
+// scanString converts the column value to string.
+func scanString(src interface{}) (string, error) {
+	switch v := src.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	return "", fmt.Errorf("encrypted column must be string or []byte: type=[%T]", src)
+}
+
+// marshalPlain converts the value to plain text.
+func marshalPlain(plain interface{}) (string, error) {
+	switch v := plain.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case encoding.TextMarshaler:
+		byt, err := v.MarshalText()
+		return string(byt), err
+	}
+	byt, err := json.Marshal(plain)
+	return string(byt), err
+}
+
+// unmarshalPlain converts plain text to the value.
+func unmarshalPlain(plainText string, plain interface{}) error {
+	switch v := plain.(type) {
+	case *string:
+		*v = plainText
+		return nil
+	case *[]byte:
+		*v = []byte(plainText)
+		return nil
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText([]byte(plainText))
+	}
+	return json.Unmarshal([]byte(plainText), plain)
+}
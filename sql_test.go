@@ -0,0 +1,176 @@
+package hierogolyph
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ driver.Valuer = EncryptedString{}
+	_ sql.Scanner   = &EncryptedString{}
+	_ driver.Valuer = Encrypted[int]{}
+	_ sql.Scanner   = &Encrypted[int]{}
+)
+
+func TestEncryptedString(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	s, err := h.Open()
+	a.NoError(err)
+	defer s.Close()
+	ctx := ContextWithEncrypter(context.Background(), s)
+
+	tests := []struct {
+		text string
+	}{
+		{"foo@example.com"},
+		{"あいうえお"},
+		{""},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		v, err := NewEncryptedString(ctx, tt.text).Value()
+		a.NoError(err, target)
+		if tt.text == "" {
+			a.Equal("", v, target)
+		} else {
+			// DB has only the envelope.
+			plainText, err := h.Decrypt(v.(string))
+			a.NoError(err, target)
+			a.Equal(tt.text, plainText, target)
+		}
+
+		for _, src := range []interface{}{v, []byte(v.(string))} {
+			scanned := EncryptedString{}
+			scanned.Bind(h)
+			a.NoError(scanned.Scan(src), target)
+			a.Equal(tt.text, scanned.Plain, target)
+		}
+	}
+
+	// NULL
+	scanned := EncryptedString{Plain: "foo"}
+	a.NoError(scanned.Scan(nil))
+	a.Equal("", scanned.Plain)
+
+	// not bound
+	_, err = EncryptedString{Plain: "foo"}.Value()
+	a.EqualError(err, "encrypter is not bound")
+	err = scanned.Scan("cipher text")
+	a.EqualError(err, "encrypter is not bound")
+	err = scanned.Scan(1)
+	a.EqualError(err, "encrypted column must be string or []byte: type=[int]")
+
+	// default Encrypter
+	SetDefaultEncrypter(h)
+	defer SetDefaultEncrypter(nil)
+	v, err := EncryptedString{Plain: "foo"}.Value()
+	a.NoError(err)
+	a.NoError(scanned.Scan(v))
+	a.Equal("foo", scanned.Plain)
+
+	// another key
+	other, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	scanned.Bind(other)
+	err = scanned.Scan(v)
+	a.True(errors.Is(err, ErrWrongKey))
+}
+
+func TestEncryptedStringBinary(t *testing.T) {
+	a := assert.New(t)
+
+	conf := testConfig
+	conf.Encoding = EncodingBinary
+	h, err := CreateHierogolyph("password", conf)
+	a.NoError(err)
+	ctx := ContextWithEncrypter(context.Background(), h)
+
+	// binary envelope is []byte for BYTEA/BLOB columns.
+	v, err := NewEncryptedString(ctx, "foo@example.com").Value()
+	a.NoError(err)
+	a.IsType([]byte{}, v)
+	scanned := EncryptedString{}
+	scanned.Bind(h)
+	a.NoError(scanned.Scan(v))
+	a.Equal("foo@example.com", scanned.Plain)
+
+	v, err = NewEncrypted(ctx, testProfile{Name: "foo", Age: 20}).Value()
+	a.NoError(err)
+	a.IsType([]byte{}, v)
+	scannedProfile := Encrypted[testProfile]{}
+	scannedProfile.Bind(h)
+	a.NoError(scannedProfile.Scan(v))
+	a.Equal(testProfile{Name: "foo", Age: 20}, scannedProfile.Plain)
+}
+
+type testProfile struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestEncrypted(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	ctx := ContextWithEncrypter(context.Background(), h)
+
+	// string is encrypted as is.
+	v, err := NewEncrypted(ctx, "foo@example.com").Value()
+	a.NoError(err)
+	plainText, err := h.Decrypt(v.(string))
+	a.NoError(err)
+	a.Equal("foo@example.com", plainText)
+
+	// struct is encrypted as JSON.
+	profile := testProfile{Name: "foo", Age: 20}
+	v, err = NewEncrypted(ctx, profile).Value()
+	a.NoError(err)
+	plainText, err = h.Decrypt(v.(string))
+	a.NoError(err)
+	a.Equal(`{"name":"foo","age":20}`, plainText)
+	scannedProfile := Encrypted[testProfile]{}
+	scannedProfile.Bind(h)
+	a.NoError(scannedProfile.Scan(v))
+	a.Equal(profile, scannedProfile.Plain)
+
+	// encoding.TextMarshaler is encrypted as text.
+	birthday := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	v, err = NewEncrypted(ctx, birthday).Value()
+	a.NoError(err)
+	plainText, err = h.Decrypt(v.(string))
+	a.NoError(err)
+	a.Equal("1990-01-02T00:00:00Z", plainText)
+	scannedTime := Encrypted[time.Time]{}
+	scannedTime.Bind(h)
+	a.NoError(scannedTime.Scan(v))
+	a.True(birthday.Equal(scannedTime.Plain))
+
+	// []byte
+	v, err = NewEncrypted(ctx, []byte("binary")).Value()
+	a.NoError(err)
+	scannedBytes := Encrypted[[]byte]{}
+	scannedBytes.Bind(h)
+	a.NoError(scannedBytes.Scan(v))
+	a.Equal([]byte("binary"), scannedBytes.Plain)
+
+	// NULL
+	a.NoError(scannedProfile.Scan(nil))
+	a.Equal(testProfile{}, scannedProfile.Plain)
+
+	// not bound
+	_, err = Encrypted[int]{Plain: 1}.Value()
+	a.EqualError(err, "encrypter is not bound")
+}
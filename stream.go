@@ -0,0 +1,268 @@
+package hierogolyph
+
+import (
+	"bufio"
+	"context"
+	stdcipher "crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/evalphobia/hierogolyph/cipher"
+)
+
+const (
+	defaultSegmentSize  = 64 * 1024
+	maxSegmentSize      = 16 * 1024 * 1024
+	maxStreamHeaderSize = 64 * 1024
+	streamSaltSize      = 32
+	streamKeySize       = 32
+)
+
+var streamKeyInfo = []byte("hierogolyph stream key")
+
+// NewEncryptWriter returns io.WriteCloser which encrypts written data and writes it into w.
+// Data is encrypted in fixed-size authenticated segments, and Unlock is called only once per stream.
+// Close must be called to write the final segment, and it does not close w.
+func (h Hierogolyph) NewEncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	c, ok := h.Config.Cipher.(cipher.AEADCipher)
+	if !ok {
+		return nil, fmt.Errorf("cipher does not support streaming: type=[%T]", h.Config.Cipher)
+	}
+
+	cek, err := h.unlock(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(cek)
+	salt, err := getRandomBytes(streamSaltSize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newStreamAEAD(c, cek, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := h.Config.newEnvelope(h.EncryptionKey, nil)
+	envelope.Payload = PayloadStream
+	envelope.SegmentSize = defaultSegmentSize
+	envelope.StreamSalt = salt
+	header, err := envelope.marshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(append(encodeUvarint(uint64(len(header))), header...)); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{
+		w:           w,
+		aead:        aead,
+		header:      header,
+		segmentSize: envelope.SegmentSize,
+		buf:         make([]byte, 0, envelope.SegmentSize),
+	}, nil
+}
+
+// NewDecryptReader returns io.Reader which decrypts data written by NewEncryptWriter from r.
+// Read returns ErrWrongKey when segments are modified or reordered, and ErrIntegrity when truncated.
+func (h Hierogolyph) NewDecryptReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxStreamHeaderSize {
+		return nil, newError(ErrMalformedCipherText, fmt.Errorf("stream header is too large: size=[%d]", size))
+	}
+
+	header := make([]byte, size)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, err
+	}
+	envelope, err := parseEnvelopeBinary(header)
+	if err != nil {
+		return nil, newError(ErrMalformedCipherText, err)
+	}
+	if envelope.Payload != PayloadStream || envelope.SegmentSize <= 0 {
+		return nil, newError(ErrMalformedCipherText, fmt.Errorf("envelope is not stream: payload=[%d], segmentSize=[%d]", envelope.Payload, envelope.SegmentSize))
+	}
+
+	h.Config, err = h.Config.resolve(envelope)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := h.Config.Cipher.(cipher.AEADCipher)
+	if !ok {
+		return nil, fmt.Errorf("cipher does not support streaming: type=[%T]", h.Config.Cipher)
+	}
+
+	cek, err := h.unlockEnvelope(context.Background(), envelope)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(cek)
+	aead, err := newStreamAEAD(c, cek, envelope.StreamSalt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		r:      br,
+		aead:   aead,
+		header: header,
+		buf:    make([]byte, envelope.SegmentSize+aead.Overhead()),
+	}, nil
+}
+
+// encryptWriter encrypts data in segments.
+type encryptWriter struct {
+	w           io.Writer
+	aead        stdcipher.AEAD
+	header      []byte
+	segmentSize int
+
+	buf     []byte
+	out     []byte
+	counter uint32
+	closed  bool
+}
+
+// Write encrypts p and writes segments when the buffer is full.
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("encrypt writer is already closed")
+	}
+
+	n := 0
+	for len(p) > 0 {
+		size := e.segmentSize - len(e.buf)
+		if size > len(p) {
+			size = len(p)
+		}
+		e.buf = append(e.buf, p[:size]...)
+		p = p[size:]
+		n += size
+
+		if len(e.buf) == e.segmentSize {
+			if err := e.flush(false); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close writes the final segment.
+// The final segment is always shorter than segmentSize, and it can be empty.
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.flush(true)
+}
+
+// flush encrypts buffered data and writes it as a segment.
+func (e *encryptWriter) flush(last bool) error {
+	if e.counter == math.MaxUint32 {
+		return errors.New("stream is too large")
+	}
+
+	nonce := segmentNonce(e.aead.NonceSize(), e.counter, last)
+	e.out = e.aead.Seal(e.out[:0], nonce, e.buf, e.header)
+	if _, err := e.w.Write(e.out); err != nil {
+		return err
+	}
+
+	e.buf = e.buf[:0]
+	e.counter++
+	return nil
+}
+
+// decryptReader decrypts data in segments.
+type decryptReader struct {
+	r      *bufio.Reader
+	aead   stdcipher.AEAD
+	header []byte
+
+	buf     []byte
+	plain   []byte
+	counter uint32
+	err     error
+}
+
+// Read decrypts segments and reads the decrypted data.
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.next()
+	}
+
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// next decrypts next segment.
+// It returns io.EOF after the final segment.
+func (d *decryptReader) next() error {
+	n, err := io.ReadFull(d.r, d.buf)
+	last := false
+	switch err {
+	case nil:
+	case io.ErrUnexpectedEOF:
+		// only the final segment is shorter than the buffer.
+		// appended data is detected here, because it makes the final segment invalid.
+		last = true
+	case io.EOF:
+		return newError(ErrIntegrity, errors.New("stream is truncated: final segment is not found"))
+	default:
+		return err
+	}
+
+	nonce := segmentNonce(d.aead.NonceSize(), d.counter, last)
+	plain, err := d.aead.Open(d.buf[:0], nonce, d.buf[:n], d.header)
+	if err != nil {
+		return newError(ErrWrongKey, fmt.Errorf("stream segment is invalid: segment=[%d]", d.counter))
+	}
+	d.plain = plain
+	d.counter++
+
+	if last {
+		return io.EOF
+	}
+	return nil
+}
+
+// newStreamAEAD returns AEAD with a key derived from CEK and salt.
+// The key is unique per stream, so the segment counter is used as nonce.
+func newStreamAEAD(c cipher.AEADCipher, cek, salt []byte) (stdcipher.AEAD, error) {
+	if len(salt) != streamSaltSize {
+		return nil, fmt.Errorf("stream salt must be %dbyte: size=[%d]", streamSaltSize, len(salt))
+	}
+
+	key := make([]byte, streamKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, cek, salt, streamKeyInfo), key); err != nil {
+		return nil, err
+	}
+	return c.NewAEAD(key)
+}
+
+// segmentNonce returns nonce of the segment, `0x00... || counter(4byte) || last flag(1byte)`.
+func segmentNonce(size int, counter uint32, last bool) []byte {
+	nonce := make([]byte, size)
+	binary.BigEndian.PutUint32(nonce[size-5:], counter)
+	if last {
+		nonce[size-1] = 1
+	}
+	return nonce
+}
@@ -0,0 +1,158 @@
+package hierogolyph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/evalphobia/hierogolyph/cipher/chacha20poly1305"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHierogolyph_Stream(t *testing.T) {
+	a := assert.New(t)
+
+	chachaConf := testConfig
+	chachaConf.Cipher = chacha20poly1305.Cipher{}
+
+	tests := []struct {
+		dataSize  int
+		chunkSize int
+	}{
+		{0, 1},
+		{1, 1},
+		{defaultSegmentSize - 1, 1000},
+		{defaultSegmentSize, defaultSegmentSize},
+		{defaultSegmentSize + 1, 7},
+		{defaultSegmentSize*3 + 7, defaultSegmentSize * 4},
+	}
+
+	for _, conf := range []Config{testConfig, chachaConf} {
+		h, err := CreateHierogolyph("password", conf)
+		a.NoError(err)
+
+		for _, tt := range tests {
+			target := fmt.Sprintf("%s %+v", conf.cipherName(), tt)
+			data, err := getRandomBytes(tt.dataSize)
+			a.NoError(err, target)
+
+			encrypted := encryptStream(t, h, data, tt.chunkSize)
+			a.True(len(encrypted) > len(data), target)
+
+			r, err := h.NewDecryptReader(bytes.NewReader(encrypted))
+			a.NoError(err, target)
+			result, err := ioutil.ReadAll(r)
+			a.NoError(err, target)
+			a.Equal(data, result, target)
+		}
+	}
+}
+
+func TestHierogolyph_StreamError(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	data, err := getRandomBytes(defaultSegmentSize*2 + 100)
+	a.NoError(err)
+	encrypted := encryptStream(t, h, data, len(data))
+
+	// split into header and segments.
+	headerSize, n := binary.Uvarint(encrypted)
+	bodyStart := n + int(headerSize)
+	segment := defaultSegmentSize + 16
+	header := encrypted[:bodyStart]
+	seg1 := encrypted[bodyStart : bodyStart+segment]
+	seg2 := encrypted[bodyStart+segment : bodyStart+segment*2]
+	seg3 := encrypted[bodyStart+segment*2:]
+
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+	tampered := append([]byte{}, encrypted...)
+	tampered[bodyStart+10] ^= 0x01
+
+	tests := []struct {
+		name       string
+		data       []byte
+		errMessage string
+	}{
+		{"truncated", join(header, seg1, seg2), "integrity check failed: stream is truncated: final segment is not found"},
+		{"truncated final segment", join(header, seg1, seg2, seg3[:50]), "key is wrong or cipherText is modified: stream segment is invalid: segment=[2]"},
+		{"reordered", join(header, seg2, seg1, seg3), "key is wrong or cipherText is modified: stream segment is invalid: segment=[0]"},
+		{"dropped", join(header, seg1, seg3), "key is wrong or cipherText is modified: stream segment is invalid: segment=[1]"},
+		{"appended", join(header, seg1, seg2, seg3, []byte("x")), "key is wrong or cipherText is modified: stream segment is invalid: segment=[2]"},
+		{"appended after final", join(encrypted, seg3), "key is wrong or cipherText is modified: stream segment is invalid: segment=[2]"},
+		{"tampered", tampered, "key is wrong or cipherText is modified: stream segment is invalid: segment=[0]"},
+	}
+
+	for _, tt := range tests {
+		r, err := h.NewDecryptReader(bytes.NewReader(tt.data))
+		a.NoError(err, tt.name)
+		_, err = ioutil.ReadAll(r)
+		a.EqualError(err, tt.errMessage, tt.name)
+	}
+
+	// different header is not authenticated.
+	h2, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	h2.Salt = h.Salt
+	a.NoError(h2.SetEncryptionKey())
+	other := encryptStream(t, h2, data, len(data))
+	otherHeaderSize, m := binary.Uvarint(other)
+	r, err := h.NewDecryptReader(bytes.NewReader(join(other[:m+int(otherHeaderSize)], seg1, seg2, seg3)))
+	a.NoError(err)
+	_, err = ioutil.ReadAll(r)
+	a.EqualError(err, "key is wrong or cipherText is modified: stream segment is invalid: segment=[0]")
+
+	// wrong password
+	h3 := h
+	h3.Password = "wrong"
+	_, err = h3.NewDecryptReader(bytes.NewReader(encrypted))
+	a.EqualError(err, "key is wrong or cipherText is modified: password is not verified")
+
+	// not a stream
+	cipherText, err := h.EncryptBytes([]byte("plain text"))
+	a.NoError(err)
+	_, err = h.NewDecryptReader(bytes.NewReader(cipherText))
+	a.Error(err)
+}
+
+func TestSegmentNonce(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		size     int
+		counter  uint32
+		last     bool
+		expected []byte
+	}{
+		{12, 0, false, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{12, 0, true, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+		{12, 258, false, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 0}},
+		{24, 1, true, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, segmentNonce(tt.size, tt.counter, tt.last), target)
+	}
+}
+
+func encryptStream(t *testing.T, h Hierogolyph, data []byte, chunkSize int) []byte {
+	a := assert.New(t)
+
+	buf := &bytes.Buffer{}
+	w, err := h.NewEncryptWriter(buf)
+	a.NoError(err)
+
+	r := bytes.NewReader(data)
+	_, err = io.CopyBuffer(struct{ io.Writer }{w}, r, make([]byte, chunkSize))
+	a.NoError(err)
+	a.NoError(w.Close())
+	return buf.Bytes()
+}
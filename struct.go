@@ -0,0 +1,408 @@
+package hierogolyph
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const piiTagName = "pii"
+
+var (
+	valuerType   = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// piiTag is parsed `pii` struct tag.
+// (e.g. `pii:"encrypt,aad=id,bidx=email_bidx"`)
+type piiTag struct {
+	encrypt bool
+	aad     string // column name of the field used for additional authenticated data.
+	bidx    string // column name of the field which stores blind index.
+}
+
+// EncryptStruct encrypts fields of v tagged by `pii`, v must be a pointer to struct.
+// Hierogolyph is unlocked only once, see Session.EncryptStruct.
+func EncryptStruct(ctx context.Context, h Hierogolyph, v interface{}) error {
+	s, err := h.OpenContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return s.EncryptStruct(v)
+}
+
+// DecryptStruct decrypts fields of v encrypted by EncryptStruct, v must be a pointer to struct.
+func DecryptStruct(ctx context.Context, h Hierogolyph, v interface{}) error {
+	s, err := h.OpenContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return s.DecryptStruct(v)
+}
+
+// EncryptStruct encrypts fields of v tagged by `pii`, and walks nested structs, pointers and slices.
+// Fields are referred by column name, which is `db` tag or snake case of the field name.
+//
+//	`pii:"encrypt"`: string, []byte, *string and []string field is encrypted in place. Empty value is not encrypted.
+//	`pii:"encrypt,aad=id"`: the value of `id` field is used for additional authenticated data.
+//	  Pointers are dereferenced (nil is empty), and driver.Valuer and fmt.Stringer are formatted by their value.
+//	`pii:"bidx=email_bidx"`: blind index of the plain value is set to `email_bidx` field by Config.BlindIndex.
+func (s *Session) EncryptStruct(v interface{}) error {
+	return s.walkStruct(v, true)
+}
+
+// DecryptStruct decrypts fields of v encrypted by EncryptStruct.
+func (s *Session) DecryptStruct(v interface{}) error {
+	return s.walkStruct(v, false)
+}
+
+// walkStruct checks v and walks it.
+func (s *Session) walkStruct(v interface{}, encrypt bool) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("v must be a pointer to struct: type=[%T]", v)
+	}
+	w := structWalker{
+		session: s,
+		encrypt: encrypt,
+		visited: make(map[visitedPointer]bool),
+	}
+	return w.walk(rv)
+}
+
+// structWalker encrypts or decrypts tagged fields.
+type structWalker struct {
+	session *Session
+	encrypt bool
+	visited map[visitedPointer]bool
+}
+
+// visitedPointer is a pointer already walked, which prevents infinite recursion of cyclic structs.
+type visitedPointer struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+// walk walks nested structs, pointers and slices.
+// The same pointer is walked only once, so the value is not encrypted twice.
+func (w structWalker) walk(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		p := visitedPointer{v.Pointer(), v.Type()}
+		if w.visited[p] {
+			return nil
+		}
+		w.visited[p] = true
+		return w.walk(v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		elem := v.Elem()
+		if elem.Kind() == reflect.Ptr {
+			return w.walk(elem)
+		}
+		// the value in the interface is not addressable, so walk its copy and set it back.
+		if !v.CanSet() {
+			return fmt.Errorf("interface value must be settable: type=[%s]", elem.Type())
+		}
+		c := reflect.New(elem.Type()).Elem()
+		c.Set(elem)
+		if err := w.walk(c); err != nil {
+			return err
+		}
+		v.Set(c)
+		return nil
+	case reflect.Struct:
+		return w.walkFields(v)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := w.walk(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// walkFields handles tagged fields of the struct, and walks others.
+func (w structWalker) walkFields(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		text, ok := f.Tag.Lookup(piiTagName)
+		if !ok {
+			if err := w.walk(v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		tag, err := parsePIITag(f.Name, text)
+		if err != nil {
+			return err
+		}
+		if err := w.handleField(v, f, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleField encrypts or decrypts the tagged field, and sets blind index.
+func (w structWalker) handleField(v reflect.Value, f reflect.StructField, tag piiTag) error {
+	fv := v.FieldByIndex(f.Index)
+	column := columnName(f)
+
+	var aad []byte
+	if tag.aad != "" {
+		ref, refTag, ok := findField(v, tag.aad)
+		switch {
+		case !ok:
+			return fmt.Errorf("pii tag refers unknown field: field=[%s] ref=[%s]", f.Name, tag.aad)
+		case refTag.encrypt:
+			return fmt.Errorf("pii tag aad must not refer encrypted field: field=[%s] ref=[%s]", f.Name, tag.aad)
+		}
+		value, err := aadValue(ref)
+		if err != nil {
+			return fmt.Errorf("pii tag aad cannot be formatted: field=[%s] ref=[%s]: %w", f.Name, tag.aad, err)
+		}
+		aad = AAD(column, value)
+	}
+
+	if w.encrypt && tag.bidx != "" {
+		if err := w.setBlindIndex(v, f, column, tag); err != nil {
+			return err
+		}
+	}
+	if !tag.encrypt {
+		return nil
+	}
+
+	s := w.session
+	return transformField(f, fv, func(byt []byte) ([]byte, error) {
+		if w.encrypt {
+			return s.encrypt(byt, aad)
+		}
+		return s.decrypt(byt, aad)
+	})
+}
+
+// setBlindIndex sets blind index of the field to the bidx field.
+func (w structWalker) setBlindIndex(v reflect.Value, f reflect.StructField, column string, tag piiTag) error {
+	index := w.session.conf.BlindIndex
+	if index == nil {
+		return errors.New("blind index is not configured")
+	}
+	target, _, ok := findField(v, tag.bidx)
+	if !ok {
+		return fmt.Errorf("pii tag refers unknown field: field=[%s] ref=[%s]", f.Name, tag.bidx)
+	}
+
+	plainText, ok := fieldBytes(v.FieldByIndex(f.Index))
+	if !ok {
+		return fmt.Errorf("pii tag is not supported: field=[%s] type=[%s]", f.Name, f.Type)
+	}
+	var token string
+	if len(plainText) != 0 {
+		var err error
+		token, err = index.Token(column, string(plainText))
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case target.Kind() == reflect.String:
+		target.SetString(token)
+	case target.Kind() == reflect.Ptr && target.Type().Elem().Kind() == reflect.String:
+		if token == "" {
+			target.Set(reflect.Zero(target.Type()))
+		} else {
+			target.Set(reflect.ValueOf(&token))
+		}
+	default:
+		return fmt.Errorf("blind index field must be string: field=[%s]", tag.bidx)
+	}
+	return nil
+}
+
+// fieldBytes returns the value of string, []byte or *string field.
+func fieldBytes(v reflect.Value) ([]byte, bool) {
+	switch {
+	case v.Kind() == reflect.String:
+		return []byte(v.String()), true
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return v.Bytes(), true
+	case v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.String:
+		if v.IsNil() {
+			return nil, true
+		}
+		return []byte(v.Elem().String()), true
+	}
+	return nil, false
+}
+
+// transformField applies fn to non-empty value of string, []byte, *string and []string field.
+func transformField(f reflect.StructField, v reflect.Value, fn func([]byte) ([]byte, error)) error {
+	switch {
+	case v.Kind() == reflect.String:
+		return transformString(v, fn)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		if v.Len() == 0 {
+			return nil
+		}
+		byt, err := fn(v.Bytes())
+		if err != nil {
+			return err
+		}
+		v.SetBytes(byt)
+		return nil
+	case v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.String:
+		if v.IsNil() {
+			return nil
+		}
+		return transformString(v.Elem(), fn)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < v.Len(); i++ {
+			if err := transformString(v.Index(i), fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("pii tag is not supported: field=[%s] type=[%s]", f.Name, f.Type)
+}
+
+// transformString applies fn to non-empty string value.
+func transformString(v reflect.Value, fn func([]byte) ([]byte, error)) error {
+	if v.Len() == 0 {
+		return nil
+	}
+	byt, err := fn([]byte(v.String()))
+	if err != nil {
+		return err
+	}
+	v.SetString(string(byt))
+	return nil
+}
+
+// aadValue returns canonical text of the field used for additional authenticated data.
+// Pointers are dereferenced and nil is empty, so the text does not depend on the address.
+func aadValue(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		if v.Kind() == reflect.Ptr && hasAADMethod(v.Type()) && !hasAADMethod(v.Type().Elem()) {
+			// driver.Valuer or fmt.Stringer has pointer receiver.
+			break
+		}
+		v = v.Elem()
+	}
+
+	if x, ok := v.Interface().(driver.Valuer); ok {
+		value, err := x.Value()
+		if err != nil {
+			return "", err
+		}
+		return formatAADValue(value), nil
+	}
+	return formatAADValue(v.Interface()), nil
+}
+
+// hasAADMethod reports whether t implements driver.Valuer or fmt.Stringer.
+func hasAADMethod(t reflect.Type) bool {
+	return t.Implements(valuerType) || t.Implements(stringerType)
+}
+
+// formatAADValue formats the value of aadValue.
+func formatAADValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x.UTC().Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return x.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// findField returns the field of the column name in the struct.
+func findField(v reflect.Value, column string) (reflect.Value, piiTag, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || columnName(f) != column {
+			continue
+		}
+		tag, _ := parsePIITag(f.Name, f.Tag.Get(piiTagName))
+		return v.Field(i), tag, true
+	}
+	return reflect.Value{}, piiTag{}, false
+}
+
+// parsePIITag parses `pii` struct tag.
+func parsePIITag(field, text string) (piiTag, error) {
+	tag := piiTag{}
+	for _, opt := range strings.Split(text, ",") {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case opt == "":
+		case opt == "encrypt":
+			tag.encrypt = true
+		case strings.HasPrefix(opt, "aad="):
+			tag.aad = strings.TrimPrefix(opt, "aad=")
+		case strings.HasPrefix(opt, "bidx="):
+			tag.bidx = strings.TrimPrefix(opt, "bidx=")
+		default:
+			return piiTag{}, fmt.Errorf("pii tag has unknown option: field=[%s] option=[%s]", field, opt)
+		}
+	}
+	return tag, nil
+}
+
+// columnName returns `db` tag or snake case of the field name.
+func columnName(f reflect.StructField) string {
+	if name := strings.Split(f.Tag.Get("db"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return toSnakeCase(f.Name)
+}
+
+// toSnakeCase converts the name to snake case. (e.g. `UserID` -> `user_id`)
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
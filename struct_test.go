@@ -0,0 +1,308 @@
+package hierogolyph
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/evalphobia/hierogolyph/pii"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUser struct {
+	ID        int64
+	Email     string   `pii:"encrypt,aad=id,bidx=email_bidx"`
+	EmailBidx string   `db:"email_bidx"`
+	Phone     *string  `pii:"encrypt"`
+	Note      []byte   `pii:"encrypt"`
+	Tags      []string `pii:"encrypt"`
+	Name      string
+	Address   *testAddress
+	Contacts  []testContact
+	private   string `pii:"encrypt"`
+}
+
+type testAddress struct {
+	PostalCode     string `pii:"encrypt,bidx=postal_code_bidx"`
+	PostalCodeBidx *string
+}
+
+type testContact struct {
+	Email string `pii:"encrypt"`
+}
+
+func TestEncryptStruct(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	h.Config.BlindIndex = &BlindIndex{
+		Key: []byte("1234567890123456"),
+		Normalizers: map[string]pii.Normalizer{
+			"email": pii.Email{},
+		},
+	}
+
+	u := newTestUser()
+	ctx := context.Background()
+	a.NoError(EncryptStruct(ctx, h, &u))
+
+	for _, v := range []string{u.Email, *u.Phone, string(u.Note), u.Tags[0], u.Address.PostalCode, u.Contacts[0].Email} {
+		_, err := ParseEnvelope(v)
+		a.NoError(err, v)
+	}
+	a.Equal("", u.Tags[1])
+	a.Equal("", u.Contacts[1].Email)
+	a.Equal("foo", u.Name)
+	a.Equal("private", u.private)
+	a.Equal(mustToken(*h.Config.BlindIndex, "email", "foo@example.com"), u.EmailBidx)
+	a.Equal(mustToken(*h.Config.BlindIndex, "postal_code", "100-0001"), *u.Address.PostalCodeBidx)
+
+	// aad is bound to id.
+	_, err = h.Decrypt(u.Email)
+	a.EqualError(err, "cipherText is bound to aad, use DecryptWithAAD")
+	plainText, err := h.DecryptWithAAD(u.Email, AAD("email", "1"))
+	a.NoError(err)
+	a.Equal("Foo@Example.com", plainText)
+
+	moved := u
+	moved.ID = 2
+	err = DecryptStruct(ctx, h, &moved)
+	a.True(errors.Is(err, ErrWrongKey))
+
+	a.NoError(DecryptStruct(ctx, h, &u))
+	expected := newTestUser()
+	expected.EmailBidx = u.EmailBidx
+	expected.Address.PostalCodeBidx = u.Address.PostalCodeBidx
+	a.Equal(expected, u)
+}
+
+func newTestUser() testUser {
+	phone := "+819012345678"
+	return testUser{
+		ID:       1,
+		Email:    "Foo@Example.com",
+		Phone:    &phone,
+		Note:     []byte("note"),
+		Tags:     []string{"a", ""},
+		Name:     "foo",
+		Address:  &testAddress{PostalCode: "100-0001"},
+		Contacts: []testContact{{Email: "bar@example.com"}, {}},
+		private:  "private",
+	}
+}
+
+func TestEncryptStructAADPointer(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	s, err := h.Open()
+	a.NoError(err)
+	defer s.Close()
+
+	type pointerID struct {
+		ID    *int64
+		Email string `pii:"encrypt,aad=id"`
+	}
+	type valuerID struct {
+		ID    sql.NullInt64
+		Email string `pii:"encrypt,aad=id"`
+	}
+
+	id := int64(5)
+	v := pointerID{ID: &id, Email: "foo@example.com"}
+	a.NoError(s.EncryptStruct(&v))
+	plainText, err := s.DecryptWithAAD(v.Email, AAD("email", "5"))
+	a.NoError(err)
+	a.Equal("foo@example.com", plainText)
+
+	// another pointer of the same id.
+	other := int64(5)
+	v.ID = &other
+	a.NoError(s.DecryptStruct(&v))
+	a.Equal("foo@example.com", v.Email)
+
+	// nil is empty.
+	v = pointerID{Email: "foo@example.com"}
+	a.NoError(s.EncryptStruct(&v))
+	_, err = s.DecryptWithAAD(v.Email, AAD("email", ""))
+	a.NoError(err)
+
+	vv := valuerID{ID: sql.NullInt64{Int64: 5, Valid: true}, Email: "foo@example.com"}
+	a.NoError(s.EncryptStruct(&vv))
+	_, err = s.DecryptWithAAD(vv.Email, AAD("email", "5"))
+	a.NoError(err)
+}
+
+type testNode struct {
+	Name string `pii:"encrypt"`
+	Next *testNode
+}
+
+func TestEncryptStructCycle(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	s, err := h.Open()
+	a.NoError(err)
+	defer s.Close()
+
+	n1 := &testNode{Name: "foo"}
+	n2 := &testNode{Name: "bar", Next: n1}
+	n1.Next = n2
+
+	a.NoError(s.EncryptStruct(n1))
+	a.NotEqual("foo", n1.Name)
+	a.NotEqual("bar", n2.Name)
+
+	// each value is encrypted only once.
+	a.NoError(s.DecryptStruct(n1))
+	a.Equal("foo", n1.Name)
+	a.Equal("bar", n2.Name)
+}
+
+func TestEncryptStructInterface(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	s, err := h.Open()
+	a.NoError(err)
+	defer s.Close()
+
+	type holder struct {
+		Value interface{}
+		Nil   interface{}
+	}
+
+	v := holder{Value: testContact{Email: "foo@example.com"}}
+	a.NoError(s.EncryptStruct(&v))
+	encrypted := v.Value.(testContact).Email
+	_, err = ParseEnvelope(encrypted)
+	a.NoError(err)
+
+	a.NoError(s.DecryptStruct(&v))
+	a.Equal(holder{Value: testContact{Email: "foo@example.com"}}, v)
+
+	// pointer in the interface is updated in place.
+	c := &testContact{Email: "foo@example.com"}
+	v = holder{Value: c}
+	a.NoError(s.EncryptStruct(&v))
+	a.NotEqual("foo@example.com", c.Email)
+	a.NoError(s.DecryptStruct(&v))
+	a.Equal("foo@example.com", c.Email)
+}
+
+type testPointerStringer struct{ v string }
+
+func (s *testPointerStringer) String() string { return s.v }
+
+func TestAADValue(t *testing.T) {
+	a := assert.New(t)
+
+	id := int64(5)
+	name := "foo"
+	var nilID *int64
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 6, time.FixedZone("JST", 9*60*60))
+
+	tests := []struct {
+		v        interface{}
+		expected string
+	}{
+		{int64(5), "5"},
+		{&id, "5"},
+		{&name, "foo"},
+		{nilID, ""},
+		{[]byte("foo"), "foo"},
+		{sql.NullInt64{Int64: 5, Valid: true}, "5"},
+		{sql.NullInt64{}, ""},
+		{&sql.NullString{String: "foo", Valid: true}, "foo"},
+		{tm, "2020-01-01T18:04:05.000000006Z"},
+		{&tm, "2020-01-01T18:04:05.000000006Z"},
+		{&testPointerStringer{"foo"}, "foo"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		v := reflect.New(reflect.TypeOf(tt.v)).Elem()
+		v.Set(reflect.ValueOf(tt.v))
+		result, err := aadValue(v)
+		a.NoError(err, target)
+		a.Equal(tt.expected, result, target)
+	}
+}
+
+func TestEncryptStructError(t *testing.T) {
+	a := assert.New(t)
+
+	h, err := CreateHierogolyph("password", testConfig)
+	a.NoError(err)
+	s, err := h.Open()
+	a.NoError(err)
+	defer s.Close()
+
+	type unknownOption struct {
+		Email string `pii:"hash"`
+	}
+	type unknownAAD struct {
+		Email string `pii:"encrypt,aad=id"`
+	}
+	type encryptedAAD struct {
+		ID    string `pii:"encrypt"`
+		Email string `pii:"encrypt,aad=id"`
+	}
+	type unsupportedType struct {
+		Age int `pii:"encrypt"`
+	}
+	type noBlindIndex struct {
+		Email     string `pii:"bidx=email_bidx"`
+		EmailBidx string
+	}
+
+	tests := []struct {
+		v          interface{}
+		errMessage string
+	}{
+		{testUser{}, "v must be a pointer to struct: type=[hierogolyph.testUser]"},
+		{(*testUser)(nil), "v must be a pointer to struct: type=[*hierogolyph.testUser]"},
+		{&unknownOption{Email: "a"}, "pii tag has unknown option: field=[Email] option=[hash]"},
+		{&unknownAAD{Email: "a"}, "pii tag refers unknown field: field=[Email] ref=[id]"},
+		{&encryptedAAD{Email: "a"}, "pii tag aad must not refer encrypted field: field=[Email] ref=[id]"},
+		{&unsupportedType{Age: 1}, "pii tag is not supported: field=[Age] type=[int]"},
+		{&noBlindIndex{Email: "a"}, "blind index is not configured"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		err := s.EncryptStruct(tt.v)
+		a.EqualError(err, tt.errMessage, target)
+	}
+}
+
+func TestToSnakeCase(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"ID", "id"},
+		{"Email", "email"},
+		{"EmailBidx", "email_bidx"},
+		{"UserID", "user_id"},
+		{"HTTPServer", "http_server"},
+		{"Address2Line", "address2_line"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+		a.Equal(tt.expected, toSnakeCase(tt.name), target)
+	}
+}